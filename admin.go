@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const adminCookie = "p4_admin"
+
+var (
+	// adminToken protège la page /admin (vide: page désactivée).
+	adminToken string
+	// shuttingDown passe à vrai à l'arrêt du serveur: /readyz répond alors 503.
+	shuttingDown atomic.Bool
+)
+
+// healthzHandler indique que le processus répond.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler indique si le serveur peut recevoir du trafic: templates chargés, stockage des parties
+// accessible en écriture, arrêt non commencé. Chaque vérification figure dans la réponse.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	var failures []string
+	for _, t := range siteTemplates {
+		if !t.loaded() {
+			failures = append(failures, "template "+t.name+" non chargé")
+		}
+	}
+	switch {
+	case store == nil:
+		failures = append(failures, "stockage des parties non initialisé")
+	default:
+		if err := store.Ping(); err != nil {
+			failures = append(failures, "stockage des parties: "+err.Error())
+		}
+	}
+	if shuttingDown.Load() {
+		failures = append(failures, "arrêt en cours")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if len(failures) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(failures, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzURL retourne l'adresse de /readyz sur la machine locale, pour le serveur configuré par cfg.
+func readyzURL(cfg ServerConfig) (string, error) {
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return "", fmt.Errorf("adresse d'écoute invalide: %q", cfg.Addr)
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + cfg.BasePath + "/readyz", nil
+}
+
+// runHealthcheck interroge /readyz du serveur local, avec les mêmes options et variables d'environnement que serve.
+// Usage: power4 healthcheck [options de serve]
+func runHealthcheck(args []string) error {
+	cfg, err := parseServerConfig(args)
+	if err != nil {
+		return err
+	}
+	url, err := readyzURL(cfg)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 3 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s: %s %s", url, res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// adminCookieValue est la valeur du cookie d'administration: une empreinte du jeton, qui n'est pas
+// conservé tel quel par le navigateur.
+func adminCookieValue() string {
+	sum := sha256.Sum256([]byte("power4-admin:" + adminToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// adminAuthorized vérifie le jeton d'administration: en-tête Authorization (Bearer) ou cookie de connexion.
+func adminAuthorized(r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return bearerAuthorized(r)
+	}
+	c, err := r.Cookie(adminCookie)
+	return err == nil && subtle.ConstantTimeCompare([]byte(c.Value), []byte(adminCookieValue())) == 1
+}
+
+// bearerAuthorized vérifie le jeton d'administration de l'en-tête Authorization (Bearer).
+func bearerAuthorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// adminPage regroupe les données de la page d'administration: connexion, liste ou détail d'une partie.
+type adminPage struct {
+	Login  bool
+	Failed bool // jeton refusé
+	Games  []AdminGame
+	Detail *AdminDetail
+}
+
+// AdminGame est une ligne de la liste des parties de /admin.
+type AdminGame struct {
+	ID         string
+	Kind       string // "locale", "salle" ou "correspondance"
+	Players    string
+	Mode       string
+	Difficulty string
+	Opponent   string
+	Cadence    string
+	Moves      int
+	State      string
+	Age        string
+	Idle       string
+	Spectators int
+}
+
+// AdminDetail est le détail d'une partie inspectée.
+type AdminDetail struct {
+	AdminGame
+	BoardHTML template.HTML
+	History   []string
+	Chat      []ChatMessage
+	Result    string
+	CSRF      string // jeton anti-CSRF des actions
+}
+
+// adminEntry résume une partie pour l'administration. Appelé avec s.mu verrouillé.
+func adminEntry(s *GameSession) AdminGame {
+	e := AdminGame{ID: s.ID, Kind: "locale", Age: formatAge(time.Since(s.Created))}
+	mutex.Lock()
+	e.Idle = formatAge(time.Since(s.Updated))
+	var names []string
+	if room := s.Room; room != nil {
+		e.Kind = "salle"
+		if s.persistent() {
+			e.Kind = "correspondance"
+		}
+		for _, p := range room.Seats {
+			if p != nil {
+				names = append(names, p.Name)
+			}
+		}
+	} else {
+		for _, p := range players {
+			if p.Solo == s {
+				names = append(names, p.Name)
+			}
+		}
+	}
+	mutex.Unlock()
+	e.Players = strings.Join(names, ", ")
+	e.Spectators = s.spectatorCount()
+
+	g := s.Game
+	switch {
+	case g == nil:
+		e.State = "aucune partie"
+		return e
+	case s.Room != nil && s.Room.waiting():
+		e.State = "en attente d'un adversaire"
+	case g.GameOver:
+		e.State = "terminée"
+	default:
+		e.State = "au tour de " + g.playerName(g.CurrentPlayer)
+	}
+	if s.Room == nil {
+		e.Players = g.Username1 + ", " + g.Username2
+	}
+	e.Mode, e.Difficulty, e.Cadence, e.Moves = g.Mode, g.Difficulty, g.TimeControl.Name, len(g.History)
+	e.Opponent = "humain"
+	if g.GameMode == ModeHumanVsAI {
+		e.Opponent = "IA " + g.aiProfile().Name
+		if g.Opponent != nil {
+			e.Opponent = "bot " + g.Opponent.Name()
+		}
+	}
+	return e
+}
+
+// formatAge affiche une durée arrondie à la seconde.
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return formatDeadline(d)
+	}
+	return d.Round(time.Second).String()
+}
+
+// adminHandler liste les parties en mémoire et permet d'en inspecter une (?id=), de la terminer
+// (action=end, match nul sans effet sur le classement) ou de la supprimer (action=delete).
+func adminHandler(w http.ResponseWriter, r *http.Request) {
+	if adminToken == "" {
+		http.Error(w, "Administration désactivée (option -admin-token)", http.StatusNotFound)
+		return
+	}
+	if r.Method == "POST" && r.FormValue("token") != "" {
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(adminToken)) != 1 {
+			logger(r.Context()).Warn("administration: jeton refusé", "remote", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			adminTmpl.Execute(w, adminPage{Login: true, Failed: true})
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: adminCookie, Value: adminCookieValue(), Path: appPath("/admin"),
+			HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.Redirect(w, r, appPath("/admin"), http.StatusSeeOther)
+		return
+	}
+	if !adminAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		adminTmpl.Execute(w, adminPage{Login: true})
+		return
+	}
+
+	if r.Method == "POST" {
+		// Le cookie de connexion part avec tout formulaire, même d'un autre site: les actions demandent
+		// le jeton anti-CSRF de la page. Le jeton en en-tête (scripts) n'est jamais envoyé par le navigateur.
+		if !bearerAuthorized(r) && !checkCSRF(r, currentPlayer(w, r)) {
+			rejectCSRF(w, r)
+			return
+		}
+		id := r.FormValue("id")
+		sess := lookupSession(id)
+		if sess == nil {
+			http.Error(w, "Partie introuvable", http.StatusNotFound)
+			return
+		}
+		switch r.FormValue("action") {
+		case "end":
+			adminEnd(r, sess)
+		case "delete":
+			adminDelete(r, sess)
+			http.Redirect(w, r, appPath("/admin"), http.StatusSeeOther)
+			return
+		default:
+			http.Error(w, "Action inconnue", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, appPath("/admin?id="+id), http.StatusSeeOther)
+		return
+	}
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		sess := lookupSession(id)
+		if sess == nil {
+			http.Error(w, "Partie introuvable", http.StatusNotFound)
+			return
+		}
+		sess.mu.Lock()
+		d := AdminDetail{AdminGame: adminEntry(sess), CSRF: currentPlayer(w, r).csrfToken()}
+		if g := sess.Game; g != nil {
+			d.BoardHTML = renderBoard(g, boardView{AnalyseURL: appPath("/analyse?id=" + sess.ID)})
+			d.Result = endMessage(g, 0)
+			for i, mv := range g.History {
+				d.History = append(d.History, fmt.Sprintf("%d. %s : emplacement %d", i+1, g.playerName(mv.Player), mv.Slot+1))
+			}
+			d.Chat = append(append(d.Chat, g.Chat[chatPlayers]...), g.Chat[chatSpectators]...)
+			sort.SliceStable(d.Chat, func(i, j int) bool { return d.Chat[i].At.Before(d.Chat[j].At) })
+		}
+		sess.mu.Unlock()
+		adminTmpl.Execute(w, adminPage{Detail: &d})
+		return
+	}
+
+	mutex.Lock()
+	list := make([]*GameSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	mutex.Unlock()
+	var games []AdminGame
+	for _, s := range list {
+		s.mu.Lock()
+		games = append(games, adminEntry(s))
+		s.mu.Unlock()
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].ID < games[j].ID })
+	adminTmpl.Execute(w, adminPage{Games: games})
+}
+
+// adminEnd termine la partie par un match nul, sans modifier le classement des joueurs.
+func adminEnd(r *http.Request, sess *GameSession) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	g := sess.Game
+	if g == nil || g.GameOver {
+		return
+	}
+	g.finish(0)
+	if sess.Room != nil {
+		sess.Room.Rated = true
+	}
+	logger(r.Context()).Info("partie terminée par l'administration", "game", sess.ID)
+	sess.touch(r.Context())
+}
+
+// adminDelete retire la partie du serveur et du stockage; les pages ouvertes sur la partie se rechargent.
+func adminDelete(r *http.Request, sess *GameSession) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	mutex.Lock()
+	delete(sessions, sess.ID)
+	for _, p := range players {
+		if p.Solo == sess {
+			p.Solo = nil
+		}
+	}
+	mutex.Unlock()
+	if store != nil {
+		if err := store.Delete(sess.ID); err != nil {
+			slog.Error("suppression de la partie", "game", sess.ID, "err", err)
+		}
+	}
+	logger(r.Context()).Info("partie supprimée par l'administration", "game", sess.ID)
+	sess.Version++
+	sess.broadcast()
+}
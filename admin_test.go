@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyzURL(t *testing.T) {
+	tests := []struct {
+		addr, base string
+		want       string
+	}{
+		{":8081", "", "http://localhost:8081/readyz"},
+		{":9000", "/power4", "http://localhost:9000/power4/readyz"},
+		{"0.0.0.0:8081", "", "http://localhost:8081/readyz"},
+		{"[::]:8081", "", "http://localhost:8081/readyz"},
+		{"127.0.0.1:8081", "", "http://127.0.0.1:8081/readyz"},
+		{"[::1]:8081", "/p4", "http://[::1]:8081/p4/readyz"},
+	}
+	for _, tt := range tests {
+		got, err := readyzURL(ServerConfig{Addr: tt.addr, BasePath: tt.base})
+		if err != nil || got != tt.want {
+			t.Errorf("readyzURL(%q, %q) = %q, %v; attendu %q", tt.addr, tt.base, got, err, tt.want)
+		}
+	}
+	if _, err := readyzURL(ServerConfig{Addr: "8081"}); err == nil {
+		t.Error("adresse sans port acceptée")
+	}
+}
+
+// useAdminToken active la page d'administration le temps du test.
+func useAdminToken(t *testing.T, token string) {
+	saved := adminToken
+	adminToken = token
+	t.Cleanup(func() { adminToken = saved })
+}
+
+// adminPost envoie une action d'administration avec le jeton en en-tête.
+func adminPost(form url.Values) *httptest.ResponseRecorder {
+	r := postForm(form)
+	r.URL.Path = "/admin"
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	adminHandler(w, r)
+	return w
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	useAdminToken(t, "")
+	w := httptest.NewRecorder()
+	adminHandler(w, httptest.NewRequest("GET", "/admin", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("sans jeton configuré: statut %d, attendu 404", w.Code)
+	}
+
+	useAdminToken(t, "secret")
+	get := func(header, cookie string) int {
+		r := httptest.NewRequest("GET", "/admin", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: adminCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		adminHandler(w, r)
+		return w.Code
+	}
+	tests := []struct {
+		name           string
+		header, cookie string
+		code           int
+	}{
+		{"sans jeton", "", "", http.StatusUnauthorized},
+		{"mauvais jeton", "Bearer faux", "", http.StatusUnauthorized},
+		{"jeton en clair dans le cookie", "", "secret", http.StatusUnauthorized},
+		{"en-tête", "Bearer secret", "", http.StatusOK},
+		{"cookie de connexion", "", adminCookieValue(), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := get(tt.header, tt.cookie); code != tt.code {
+			t.Errorf("%s: statut %d, attendu %d", tt.name, code, tt.code)
+		}
+	}
+
+	// Le formulaire de connexion dépose le cookie
+	w = httptest.NewRecorder()
+	adminHandler(w, postForm(url.Values{"token": {"secret"}}))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Value != adminCookieValue() {
+		t.Errorf("connexion: statut %d, cookies %v", w.Code, cookies)
+	}
+}
+
+func TestAdminEnd(t *testing.T) {
+	useAdminToken(t, "secret")
+	alice := &Player{ID: newID(), Name: "Alice", Rating: 1600}
+	bob := &Player{ID: newID(), Name: "Bob", Rating: 1400}
+	room := &Room{RoomSettings: RoomSettings{Difficulty: "easy", Mode: "normal", BestOf: 1}, Seats: [2]*Player{alice, bob}, Created: time.Now()}
+	sess := newSession(room)
+	sess.Game = newRoomGame(room, nil)
+	sess.Game.DropToken(3)
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		mutex.Unlock()
+	})
+
+	w := adminPost(url.Values{"action": {"end"}, "id": {sess.ID}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/admin?id="+sess.ID {
+		t.Fatalf("fin de partie: statut %d vers %q", w.Code, w.Header().Get("Location"))
+	}
+	sess.mu.Lock()
+	g, version := sess.Game, sess.Version
+	sess.settle()
+	sess.mu.Unlock()
+	if !g.GameOver || g.Winner != 0 {
+		t.Fatalf("partie: GameOver=%v Winner=%d, attendu un match nul", g.GameOver, g.Winner)
+	}
+	if version == 0 {
+		t.Error("les pages abonnées ne sont pas prévenues")
+	}
+	// Le nul imposé ne compte pas au classement
+	if alice.Rating != 1600 || bob.Rating != 1400 {
+		t.Errorf("classement modifié: %d et %d", alice.Rating, bob.Rating)
+	}
+
+	if w := adminPost(url.Values{"action": {"end"}, "id": {"inconnu"}}); w.Code != http.StatusNotFound {
+		t.Errorf("partie inconnue: statut %d", w.Code)
+	}
+	if w := adminPost(url.Values{"action": {"pause"}, "id": {sess.ID}}); w.Code != http.StatusBadRequest {
+		t.Errorf("action inconnue: statut %d", w.Code)
+	}
+}
+
+func TestAdminDelete(t *testing.T) {
+	useAdminToken(t, "secret")
+	sess := newSession(nil)
+	sess.Game = newTestGame("normal")
+	p := &Player{ID: newID(), Name: "Alice", Solo: sess}
+	mutex.Lock()
+	players[p.ID] = p
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		delete(players, p.ID)
+		mutex.Unlock()
+	})
+	sub := &subscriber{notify: make(chan struct{}, 1)}
+	sess.mu.Lock()
+	sess.subscribe(sub)
+	sess.mu.Unlock()
+	<-sub.notify
+
+	w := adminPost(url.Values{"action": {"delete"}, "id": {sess.ID}})
+	if w.Code != http.StatusSeeOther || !strings.HasSuffix(w.Header().Get("Location"), "/admin") {
+		t.Fatalf("suppression: statut %d vers %q", w.Code, w.Header().Get("Location"))
+	}
+	if lookupSession(sess.ID) != nil {
+		t.Error("partie toujours listée")
+	}
+	mutex.Lock()
+	solo := p.Solo
+	mutex.Unlock()
+	if solo != nil {
+		t.Error("le joueur garde la partie supprimée")
+	}
+	select {
+	case <-sub.notify:
+	default:
+		t.Error("les pages abonnées ne sont pas prévenues")
+	}
+}
+
+func TestAdminActionsNeedCSRF(t *testing.T) {
+	useAdminToken(t, "secret")
+	sess := newSession(nil)
+	sess.Game = newTestGame("normal")
+	p := &Player{ID: newID(), Name: "Admin"}
+	mutex.Lock()
+	players[p.ID] = p
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		delete(players, p.ID)
+		mutex.Unlock()
+	})
+	// Connexion par cookie, comme depuis la page d'administration
+	post := func(form url.Values) int {
+		r := postForm(form)
+		r.URL.Path = "/admin"
+		r.AddCookie(&http.Cookie{Name: adminCookie, Value: adminCookieValue()})
+		r.AddCookie(&http.Cookie{Name: playerCookie, Value: p.ID})
+		w := httptest.NewRecorder()
+		adminHandler(w, r)
+		return w.Code
+	}
+	if code := post(url.Values{"action": {"end"}, "id": {sess.ID}}); code != http.StatusForbidden {
+		t.Fatalf("action sans jeton anti-CSRF: statut %d, attendu 403", code)
+	}
+	if sess.Game.GameOver {
+		t.Fatal("partie terminée sans jeton anti-CSRF")
+	}
+	if code := post(url.Values{"action": {"end"}, "id": {sess.ID}, "csrf": {p.csrfToken()}}); code != http.StatusSeeOther {
+		t.Fatalf("action avec le jeton de la page: statut %d", code)
+	}
+}
+
+func TestAdminEntrySpectators(t *testing.T) {
+	sess := &GameSession{ID: "abc", Created: time.Now(), Updated: time.Now()}
+	sess.Game = newTestGame("normal")
+	// Deux onglets du même visiteur et un second visiteur
+	for _, player := range []string{"p1", "p1", "p2"} {
+		sess.subscribe(&subscriber{notify: make(chan struct{}, 1), player: player, spectator: true})
+	}
+	sess.subscribe(&subscriber{notify: make(chan struct{}, 1), player: "p3"})
+	if e := adminEntry(sess); e.Spectators != 2 {
+		t.Errorf("%d spectateurs, attendu 2", e.Spectators)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAnalysisTimeLimit = 600 * time.Millisecond // par coup
+	analysisMaxDepth         = 10
+	graphClamp               = 120 // les évaluations sont bornées à ±graphClamp sur le graphe
+)
+
+// analysisTimeLimit est le temps d'analyse par coup de /analyse (réglable au lancement du serveur).
+var analysisTimeLimit = defaultAnalysisTimeLimit
+
+// MoveReport est l'analyse d'un coup joué.
+type MoveReport struct {
+	Number        int
+	Move          Move
+	Name          string
+	Horizontal    bool
+	Best          int    // meilleur emplacement selon le moteur
+	BestVerdict   string // verdict du meilleur coup
+	PlayedVerdict string // verdict du coup joué
+	Loss          int    // écart de score entre le meilleur coup et le coup joué
+	Blunder       bool   // position gagnée ou nulle transformée en défaite
+	MissedWin     bool   // un gain forcé existait mais n'a pas été joué
+}
+
+// GameReport est le rapport d'analyse d'une partie terminée.
+type GameReport struct {
+	Moves    []MoveReport
+	Evals    []int // évaluation avant chaque coup puis finale, du point de vue du joueur 1
+	Blunders [2]int
+	Missed   [2]int
+}
+
+// replayStart retourne une copie de la partie ramenée à sa position initiale.
+func (g *Game) replayStart() *Game {
+	c := g.clone()
+	c.Board = make([][]int, g.Rows)
+	for r := range g.InitialBoard {
+		c.Board[r] = append([]int(nil), g.InitialBoard[r]...)
+	}
+	c.Gravity = g.InitialGravity
+	c.History = nil
+	c.TurnCount = 0
+	c.GameOver = false
+	c.Winner = 0
+	c.TimedOut = false
+	c.LastRow, c.LastCol = -1, -1
+	if len(g.History) > 0 {
+		c.CurrentPlayer = g.History[0].Player
+	}
+	return c
+}
+
+// analyseGame rejoue la partie et réévalue chaque coup avec une recherche plus profonde que celle de l'IA.
+func analyseGame(g *Game, maxDepth int, limit time.Duration) GameReport {
+	var report GameReport
+	c := g.replayStart()
+	for i, mv := range g.History {
+		scores, _ := analyseMoves(c, maxDepth, limit)
+		mr := MoveReport{
+			Number:     i + 1,
+			Move:       mv,
+			Name:       g.playerName(mv.Player),
+			Horizontal: c.Gravity.Horizontal(),
+			Best:       bestSlot(scores),
+		}
+		bestScore, playedScore := 0, 0
+		for _, s := range scores {
+			if s.Slot == mr.Best {
+				bestScore, mr.BestVerdict = s.Score, s.Verdict
+			}
+			if s.Slot == mv.Slot {
+				playedScore, mr.PlayedVerdict = s.Score, s.Verdict
+			}
+		}
+		mr.Loss = bestScore - playedScore
+		mr.Blunder = mr.BestVerdict != "perdant" && mr.PlayedVerdict == "perdant"
+		mr.MissedWin = mr.BestVerdict == "gagnant" && mr.PlayedVerdict != "gagnant"
+		if mr.Blunder {
+			report.Blunders[mv.Player-1]++
+		}
+		if mr.MissedWin {
+			report.Missed[mv.Player-1]++
+		}
+		if mv.Player == 2 {
+			bestScore = -bestScore
+		}
+		report.Evals = append(report.Evals, bestScore)
+		report.Moves = append(report.Moves, mr)
+
+		if !c.DropToken(mv.Slot) {
+			break
+		}
+	}
+	switch g.Winner {
+	case 1:
+		report.Evals = append(report.Evals, winScore)
+	case 2:
+		report.Evals = append(report.Evals, -winScore)
+	default:
+		report.Evals = append(report.Evals, 0)
+	}
+	return report
+}
+
+// playerName retourne le nom affiché du joueur 1 ou 2.
+func (g *Game) playerName(player int) string {
+	if player == 1 {
+		return g.Username1
+	}
+	return g.Username2
+}
+
+// renderEvalGraph génère un graphe SVG de l'évaluation au fil de la partie (au-dessus de l'axe: avantage joueur 1).
+func renderEvalGraph(evals []int) template.HTML {
+	const width, height = 640, 200
+	if len(evals) < 2 {
+		return ""
+	}
+	step := float64(width) / float64(len(evals)-1)
+	points := ""
+	for i, e := range evals {
+		if e > graphClamp {
+			e = graphClamp
+		} else if e < -graphClamp {
+			e = -graphClamp
+		}
+		x := float64(i) * step
+		y := float64(height)/2 - float64(e)*float64(height)/2/graphClamp
+		points += strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64) + " "
+	}
+	html := "<svg class='eval-graph' viewBox='0 0 " + strconv.Itoa(width) + " " + strconv.Itoa(height) + "' preserveAspectRatio='none' role='img' aria-label='Evaluation au fil de la partie'>"
+	html += "<line class='eval-axis' x1='0' y1='" + strconv.Itoa(height/2) + "' x2='" + strconv.Itoa(width) + "' y2='" + strconv.Itoa(height/2) + "'/>"
+	html += "<polyline class='eval-line' points='" + points + "'/>"
+	html += "</svg>"
+	return template.HTML(html)
+}
+
+// analysisBackURL retourne la page de la partie analysée: la salle en ligne, la partie regardée
+// ou la partie locale du visiteur. Appelé avec s.mu verrouillé.
+func analysisBackURL(s *GameSession, r *http.Request) string {
+	switch {
+	case s.Room != nil:
+		return appPath("/room?id=" + s.ID)
+	case r.URL.Query().Get("id") != "":
+		return appPath("/watch?id=" + s.ID)
+	default:
+		return appPath("/connect4")
+	}
+}
+
+// analyseHandler affiche le rapport d'analyse de la partie terminée.
+// L'analyse se fait sur une copie, sans garder le verrou de la session.
+func analyseHandler(w http.ResponseWriter, r *http.Request) {
+	sess := requestSession(w, r)
+	if sess == nil {
+		http.Error(w, "Partie introuvable", http.StatusNotFound)
+		return
+	}
+	sess.mu.Lock()
+	game := sess.Game
+	if game == nil || !game.GameOver {
+		sess.mu.Unlock()
+		http.Error(w, "Aucune partie terminée à analyser", http.StatusNotFound)
+		return
+	}
+	snapshot := game.clone()
+	backURL := analysisBackURL(sess, r)
+	sess.mu.Unlock()
+
+	release, ok := acquireSearch(w, r)
+	if !ok {
+		return
+	}
+	report := analyseGame(snapshot, analysisMaxDepth, analysisTimeLimit)
+	release()
+	analysisTmpl.Execute(w, struct {
+		Username1 string
+		Username2 string
+		Skin      string
+		Report    GameReport
+		Graph     template.HTML
+		BackURL   string
+	}{
+		Username1: snapshot.Username1,
+		Username2: snapshot.Username2,
+		Skin:      snapshot.Skin,
+		Report:    report,
+		Graph:     renderEvalGraph(report.Evals),
+		BackURL:   backURL,
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnalysisBackURL(t *testing.T) {
+	saved := basePath
+	basePath = "/p4"
+	t.Cleanup(func() { basePath = saved })
+
+	local := &GameSession{ID: "abc"}
+	room := &GameSession{ID: "def", Room: &Room{}}
+	tests := []struct {
+		sess   *GameSession
+		target string
+		want   string
+	}{
+		{local, "/analyse", "/p4/connect4"},
+		{local, "/analyse?id=abc", "/p4/watch?id=abc"},
+		{room, "/analyse?id=def", "/p4/room?id=def"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		r.Header.Set("Referer", "https://ailleurs.example/")
+		if got := analysisBackURL(tt.sess, r); got != tt.want {
+			t.Errorf("%s: lien de retour %q, attendu %q", tt.target, got, tt.want)
+		}
+	}
+}
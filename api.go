@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// GameState est l'état d'une partie renvoyé en JSON par /api/game (client terminal distant).
+type GameState struct {
+	ID            string    `json:"id,omitempty"`
+	Rows          int       `json:"rows"`
+	Cols          int       `json:"cols"`
+	Mode          string    `json:"mode"`
+	Gravity       string    `json:"gravity"`
+	Board         [][]int   `json:"board"`
+	Players       [2]string `json:"players"`
+	VsAI          bool      `json:"vs_ai"`
+	CurrentPlayer int       `json:"current_player"`
+	GameOver      bool      `json:"game_over"`
+	Winner        int       `json:"winner"`
+	Message       string    `json:"message,omitempty"`
+	LastRow       int       `json:"last_row"`
+	LastCol       int       `json:"last_col"`
+	Winning       [][2]int  `json:"winning,omitempty"`
+	ValidMoves    []int     `json:"valid_moves"`
+	History       []int     `json:"history"`
+	Timed         bool      `json:"timed"`
+	TimeLeftMs    [2]int64  `json:"time_left_ms"`
+}
+
+// gameState résume la partie vue depuis la place seat (voir endMessage).
+func gameState(g *Game, seat int) GameState {
+	st := GameState{
+		Rows:          g.Rows,
+		Cols:          g.Cols,
+		Mode:          g.Mode,
+		Gravity:       gravityName(g.Gravity),
+		Board:         g.Board,
+		Players:       [2]string{g.Username1, g.Username2},
+		VsAI:          g.GameMode == ModeHumanVsAI,
+		CurrentPlayer: g.CurrentPlayer,
+		GameOver:      g.GameOver,
+		Winner:        g.Winner,
+		Message:       endMessage(g, seat),
+		LastRow:       g.LastRow,
+		LastCol:       g.LastCol,
+		Winning:       g.getWinningPositions(),
+		ValidMoves:    g.getValidMoves(),
+		History:       []int{},
+		Timed:         g.TimeControl.Enabled(),
+	}
+	for _, mv := range g.History {
+		st.History = append(st.History, mv.Slot)
+	}
+	if st.Timed {
+		st.TimeLeftMs = [2]int64{g.TimeLeft(1).Milliseconds(), g.TimeLeft(2).Milliseconds()}
+	}
+	return st
+}
+
+// localSeat retourne la place du joueur d'une partie locale: 1 contre l'IA, 0 (neutre) à deux sur le même écran.
+func localSeat(g *Game) int {
+	if g.GameMode == ModeHumanVsAI {
+		return 1
+	}
+	return 0
+}
+
+// apiHandler expose la partie locale du visiteur en JSON: GET retourne l'état, POST avec action=new
+// lance une partie (mêmes paramètres que /connect4, validés) et POST avec slot=N joue un coup (l'IA répond aussitôt).
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	player := currentPlayer(w, r)
+	sess := player.solo()
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if r.Method == "POST" {
+		switch {
+		case r.FormValue("action") == "new":
+			cfg, err := parseGameConfig(r.Form)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			player.rename(cfg.Username) // déjà validé par parseGameConfig
+			mutex.Lock()
+			username := player.Name
+			mutex.Unlock()
+			rows, cols, prefill := boardSize(cfg.Difficulty)
+			g := NewGame(rows, cols, prefill, cfg.Difficulty, username, cfg.Username2,
+				normalizeMode(cfg.Mode), cfg.Skin, cfg.GameMode, parseAILevel(cfg.AILevel))
+			g.SetTimeControl(parseTimeControl(cfg.Cadence))
+			g.Profile = lookupProfile(cfg.AILevel, g.AILevel)
+			g.Opponent = externalBot(cfg.AILevel)
+			g.playAIMoveIfNeeded()
+			sess.Game = g
+		case sess.Game == nil:
+			http.Error(w, "Aucune partie en cours", http.StatusNotFound)
+			return
+		default:
+			slot, err := strconv.Atoi(r.FormValue("slot"))
+			if err != nil {
+				http.Error(w, "Coup invalide", http.StatusBadRequest)
+				return
+			}
+			// La place de recherche est réservée avant le coup: l'IA répond toujours
+			if sess.Game.GameMode == ModeHumanVsAI {
+				release, ok := sess.acquireSearch(w, r)
+				if !ok {
+					return
+				}
+				defer release()
+				// La partie a pu changer pendant l'attente d'une place
+				if sess.Game == nil {
+					http.Error(w, "Aucune partie en cours", http.StatusNotFound)
+					return
+				}
+			}
+			game := sess.Game
+			if game.checkTimeout() || game.GameOver || (game.GameMode == ModeHumanVsAI && game.CurrentPlayer != 1) {
+				sess.touch(r.Context())
+				http.Error(w, "Ce n'est pas à vous de jouer", http.StatusConflict)
+				return
+			}
+			if !game.DropToken(slot) {
+				http.Error(w, "Coup illégal", http.StatusBadRequest)
+				return
+			}
+			game.playAIMoveIfNeeded()
+		}
+		sess.touch(r.Context())
+	}
+
+	if sess.Game == nil {
+		http.Error(w, "Aucune partie en cours", http.StatusNotFound)
+		return
+	}
+	sess.Game.checkTimeout()
+	st := gameState(sess.Game, localSeat(sess.Game))
+	st.ID = sess.ID
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
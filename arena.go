@@ -0,0 +1,535 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bot est un joueur automatique: il choisit un emplacement pour la position donnée.
+type Bot interface {
+	Name() string
+	Label() string
+	Move(g *Game) (int, error)
+}
+
+// profileBot fait jouer un profil d'IA interne.
+type profileBot struct {
+	profile *AIProfile
+}
+
+func (b profileBot) Name() string  { return b.profile.Name }
+func (b profileBot) Label() string { return b.profile.Label }
+
+func (b profileBot) Move(g *Game) (int, error) {
+	slot := g.profileMove(b.profile)
+	if slot < 0 {
+		return -1, errors.New("aucun coup jouable")
+	}
+	return slot, nil
+}
+
+// resolveBot retourne le joueur automatique désigné par son nom (un profil d'IA, un moteur externe ou un bot HTTP).
+func resolveBot(name string) (Bot, error) {
+	if p, ok := aiProfiles[name]; ok {
+		return profileBot{profile: p}, nil
+	}
+	if b := externalBot(name); b != nil {
+		return b, nil
+	}
+	return nil, fmt.Errorf("joueur inconnu: %q", name)
+}
+
+// externalBot retourne le moteur externe ou le bot HTTP désigné par son nom, ou nil.
+func externalBot(name string) Bot {
+	if e, ok := engines[name]; ok {
+		return engineBot{engine: e}
+	}
+	if b, ok := webhooks[name]; ok {
+		return b
+	}
+	return nil
+}
+
+// arenaPath est le fichier de résultats du dernier tournoi, affiché par /arena.
+const arenaPath = "arena.json"
+
+// TournamentConfig décrit un tournoi entre joueurs automatiques.
+type TournamentConfig struct {
+	Format  string   `json:"format"` // "round-robin" ou "swiss"
+	Rounds  int      `json:"rounds"` // cycles (toutes rondes) ou rondes (suisse)
+	Rows    int      `json:"rows"`
+	Cols    int      `json:"cols"`
+	Mode    string   `json:"mode"`
+	Cadence string   `json:"cadence"`
+	Players []string `json:"players"`
+}
+
+// ArenaGame est le résultat d'une partie du tournoi; First commence et joue les jetons du joueur 1.
+type ArenaGame struct {
+	Round  int    `json:"round"`
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Winner string `json:"winner,omitempty"` // vide pour un nul
+	Moves  int    `json:"moves"`
+	Reason string `json:"reason,omitempty"` // "temps", "forfait", ...
+}
+
+// PairResult cumule les parties entre deux joueurs, vues de A.
+type PairResult struct {
+	A         string  `json:"a"`
+	B         string  `json:"b"`
+	Games     int     `json:"games"`
+	WinsA     int     `json:"wins_a"`
+	WinsB     int     `json:"wins_b"`
+	Draws     int     `json:"draws"`
+	AFirst    int     `json:"a_first"` // parties où A commençait
+	BFirst    int     `json:"b_first"`
+	ScoreA    float64 `json:"score_a"`
+	ScoreText string  `json:"score_text"`
+}
+
+// Standing est le classement d'un joueur avec son estimation Elo et son intervalle de confiance à 95%.
+type Standing struct {
+	Rank     int     `json:"rank"`
+	Name     string  `json:"name"`
+	Games    int     `json:"games"`
+	Score    float64 `json:"score"`
+	First    int     `json:"first"`          // parties jouées en commençant
+	Byes     int     `json:"byes,omitempty"` // rondes exemptées (suisse): un point chacune, compté dans Score mais pas dans Games
+	Elo      float64 `json:"elo"`
+	EloError float64 `json:"elo_error"`
+}
+
+// TournamentResult est la sortie d'un tournoi (JSON et page web).
+type TournamentResult struct {
+	Config     TournamentConfig `json:"config"`
+	Started    time.Time        `json:"started"`
+	Games      []ArenaGame      `json:"games"`
+	Standings  []Standing       `json:"standings"`
+	Pairs      []PairResult     `json:"pairs"`
+	Crosstable [][]string       `json:"crosstable"` // Crosstable[i][j]: score de Standings[i] contre Standings[j]
+}
+
+// playArenaGame joue une partie entre deux joueurs automatiques; un coup refusé ou une erreur du bot est un forfait,
+// dont la cause est écrite sur progress.
+func playArenaGame(cfg TournamentConfig, first, second Bot, progress io.Writer) ArenaGame {
+	g := NewGame(cfg.Rows, cfg.Cols, 0, "", first.Name(), second.Name(), cfg.Mode, "", ModeHumanVsHuman, AIEasy)
+	g.SetTimeControl(parseTimeControl(cfg.Cadence))
+	result := ArenaGame{First: first.Name(), Second: second.Name()}
+	for !g.GameOver {
+		bot := first
+		if g.CurrentPlayer == 2 {
+			bot = second
+		}
+		slot, err := bot.Move(g)
+		if g.checkTimeout() {
+			result.Reason = "temps"
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(progress, "%s: %v\n", bot.Name(), err)
+		}
+		if err != nil || !g.DropToken(slot) {
+			result.Reason = "forfait"
+			g.finish(3 - g.CurrentPlayer)
+			break
+		}
+	}
+	switch g.Winner {
+	case 1:
+		result.Winner = first.Name()
+	case 2:
+		result.Winner = second.Name()
+	}
+	result.Moves = len(g.History)
+	return result
+}
+
+// arenaScore retourne le score (1, 0.5 ou 0) de name dans la partie.
+func arenaScore(game ArenaGame, name string) float64 {
+	switch game.Winner {
+	case "":
+		return 0.5
+	case name:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// roundRobinPairings retourne les appariements de toutes les rondes: chaque paire se rencontre deux fois
+// par cycle, chacun commençant une fois.
+func roundRobinPairings(players []string, cycles int) [][][2]string {
+	var rounds [][][2]string
+	for c := 0; c < cycles; c++ {
+		for _, swap := range []bool{false, true} {
+			var round [][2]string
+			for i := 0; i < len(players); i++ {
+				for j := i + 1; j < len(players); j++ {
+					if swap {
+						round = append(round, [2]string{players[j], players[i]})
+					} else {
+						round = append(round, [2]string{players[i], players[j]})
+					}
+				}
+			}
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// swissPairings apparie les joueurs de score voisin sans revanche si possible; celui qui a le moins
+// commencé prend le trait. En nombre impair, le joueur le moins bien classé parmi ceux qui ont été
+// le moins souvent exemptés reçoit l'exemption.
+func swissPairings(players []string, score map[string]float64, firsts, byes map[string]int, met map[[2]string]bool) (pairs [][2]string, bye string) {
+	order := append([]string(nil), players...)
+	sort.SliceStable(order, func(i, j int) bool { return score[order[i]] > score[order[j]] })
+	if len(order)%2 == 1 {
+		k := len(order) - 1
+		for i := len(order) - 2; i >= 0; i-- {
+			if byes[order[i]] < byes[order[k]] {
+				k = i
+			}
+		}
+		bye = order[k]
+		order = append(order[:k], order[k+1:]...)
+	}
+	used := map[string]bool{}
+	for i, a := range order {
+		if used[a] {
+			continue
+		}
+		partner := ""
+		for _, b := range order[i+1:] {
+			if !used[b] && !met[[2]string{a, b}] {
+				partner = b
+				break
+			}
+		}
+		if partner == "" {
+			for _, b := range order[i+1:] {
+				if !used[b] {
+					partner = b
+					break
+				}
+			}
+		}
+		if partner == "" {
+			continue
+		}
+		used[a], used[partner] = true, true
+		if firsts[partner] < firsts[a] {
+			pairs = append(pairs, [2]string{partner, a})
+		} else {
+			pairs = append(pairs, [2]string{a, partner})
+		}
+	}
+	return pairs, bye
+}
+
+// runTournament joue le tournoi complet; le résultat de chaque partie est écrit sur progress.
+func runTournament(cfg TournamentConfig, progress io.Writer) (*TournamentResult, error) {
+	if cfg.Format != "round-robin" && cfg.Format != "swiss" {
+		return nil, fmt.Errorf("format de tournoi inconnu: %q (round-robin ou swiss)", cfg.Format)
+	}
+	bots := map[string]Bot{}
+	for _, name := range cfg.Players {
+		// Les résultats sont indexés par nom: un joueur inscrit deux fois fausserait appariements et classement
+		if _, ok := bots[name]; ok {
+			return nil, fmt.Errorf("joueur inscrit deux fois: %q", name)
+		}
+		b, err := resolveBot(name)
+		if err != nil {
+			return nil, err
+		}
+		bots[name] = b
+	}
+	if len(bots) < 2 {
+		return nil, errors.New("il faut au moins deux joueurs")
+	}
+	res := &TournamentResult{Config: cfg, Started: time.Now()}
+	score := map[string]float64{}
+	firsts := map[string]int{}
+	byes := map[string]int{}
+	met := map[[2]string]bool{}
+	play := func(round int, pair [2]string) {
+		game := playArenaGame(cfg, bots[pair[0]], bots[pair[1]], progress)
+		game.Round = round
+		res.Games = append(res.Games, game)
+		score[pair[0]] += arenaScore(game, pair[0])
+		score[pair[1]] += arenaScore(game, pair[1])
+		firsts[pair[0]]++
+		met[[2]string{pair[0], pair[1]}] = true
+		met[[2]string{pair[1], pair[0]}] = true
+		fmt.Fprintf(progress, "ronde %d: %s - %s: %s\n", round, pair[0], pair[1], strings.TrimSpace(game.Winner+" "+game.Reason))
+	}
+
+	switch cfg.Format {
+	case "swiss":
+		for round := 1; round <= cfg.Rounds; round++ {
+			pairs, bye := swissPairings(cfg.Players, score, firsts, byes, met)
+			for _, pair := range pairs {
+				play(round, pair)
+			}
+			if bye != "" {
+				score[bye]++
+				byes[bye]++
+				fmt.Fprintf(progress, "ronde %d: %s exempt\n", round, bye)
+			}
+		}
+	case "round-robin":
+		for i, round := range roundRobinPairings(cfg.Players, cfg.Rounds) {
+			for _, pair := range round {
+				play(i+1, pair)
+			}
+		}
+	}
+	res.summarise(score, byes)
+	return res, nil
+}
+
+// summarise calcule le classement, les estimations Elo, les résultats par paire et le tableau croisé.
+func (res *TournamentResult) summarise(score map[string]float64, byes map[string]int) {
+	players := res.Config.Players
+	index := map[string]int{}
+	for i, p := range players {
+		index[p] = i
+	}
+	n := len(players)
+	games := make([][]int, n)      // parties entre i et j
+	points := make([][]float64, n) // points de i contre j
+	for i := range games {
+		games[i] = make([]int, n)
+		points[i] = make([]float64, n)
+	}
+	pairs := map[[2]int]*PairResult{}
+	standings := make([]Standing, n)
+	for i, p := range players {
+		standings[i] = Standing{Name: p, Score: score[p], Byes: byes[p]}
+	}
+	for _, g := range res.Games {
+		a, b := index[g.First], index[g.Second]
+		games[a][b]++
+		games[b][a]++
+		points[a][b] += arenaScore(g, g.First)
+		points[b][a] += arenaScore(g, g.Second)
+		standings[a].Games++
+		standings[b].Games++
+		standings[a].First++
+
+		i, j := min(a, b), max(a, b)
+		pr := pairs[[2]int{i, j}]
+		if pr == nil {
+			pr = &PairResult{A: players[i], B: players[j]}
+			pairs[[2]int{i, j}] = pr
+		}
+		pr.Games++
+		switch g.Winner {
+		case players[i]:
+			pr.WinsA++
+		case players[j]:
+			pr.WinsB++
+		default:
+			pr.Draws++
+		}
+		if g.First == players[i] {
+			pr.AFirst++
+		} else {
+			pr.BFirst++
+		}
+		pr.ScoreA = float64(pr.WinsA) + float64(pr.Draws)/2
+		pr.ScoreText = fmt.Sprintf("%.1f - %.1f", pr.ScoreA, float64(pr.Games)-pr.ScoreA)
+	}
+
+	elo, eloErr := estimateElo(games, points)
+	for i := range standings {
+		standings[i].Elo = math.Round(elo[i])
+		standings[i].EloError = math.Round(eloErr[i])
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		if standings[order[x]].Score != standings[order[y]].Score {
+			return standings[order[x]].Score > standings[order[y]].Score
+		}
+		return standings[order[x]].Elo > standings[order[y]].Elo
+	})
+	res.Standings = nil
+	res.Crosstable = nil
+	for rank, i := range order {
+		standings[i].Rank = rank + 1
+		res.Standings = append(res.Standings, standings[i])
+		var row []string
+		for _, j := range order {
+			switch {
+			case i == j:
+				row = append(row, "x")
+			case games[i][j] == 0:
+				row = append(row, "")
+			default:
+				row = append(row, fmt.Sprintf("%g/%d", points[i][j], games[i][j]))
+			}
+		}
+		res.Crosstable = append(res.Crosstable, row)
+	}
+	res.Pairs = nil
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if pr := pairs[[2]int{i, j}]; pr != nil {
+				res.Pairs = append(res.Pairs, *pr)
+			}
+		}
+	}
+}
+
+// estimateElo ajuste un modèle de Bradley-Terry (nuls comptés pour moitié) par itérations MM,
+// puis convertit en Elo centré sur 1500 avec l'erreur type (×1,96 pour un intervalle à 95%).
+func estimateElo(games [][]int, points [][]float64) (elo, errs []float64) {
+	n := len(games)
+	strength := make([]float64, n)
+	for i := range strength {
+		strength[i] = 1
+	}
+	for iter := 0; iter < 500; iter++ {
+		for i := 0; i < n; i++ {
+			// Un score nul ou parfait diverge: on ajoute un demi-nul fictif contre un adversaire moyen
+			won, denom := 0.25, 0.5/(strength[i]+1)
+			for j := 0; j < n; j++ {
+				if i == j || games[i][j] == 0 {
+					continue
+				}
+				won += points[i][j]
+				denom += float64(games[i][j]) / (strength[i] + strength[j])
+			}
+			strength[i] = won / denom
+		}
+		// Normalisation: moyenne géométrique 1
+		logSum := 0.0
+		for _, s := range strength {
+			logSum += math.Log(s)
+		}
+		norm := math.Exp(logSum / float64(n))
+		for i := range strength {
+			strength[i] /= norm
+		}
+	}
+	scale := 400 / math.Ln10
+	elo = make([]float64, n)
+	errs = make([]float64, n)
+	for i := 0; i < n; i++ {
+		elo[i] = 1500 + scale*math.Log(strength[i])
+		info := 0.0
+		for j := 0; j < n; j++ {
+			if i == j || games[i][j] == 0 {
+				continue
+			}
+			p := strength[i] / (strength[i] + strength[j])
+			info += float64(games[i][j]) * p * (1 - p)
+		}
+		if info > 0 {
+			errs[i] = 1.96 * scale / math.Sqrt(info)
+		}
+	}
+	return elo, errs
+}
+
+// loadTournamentResult lit les résultats du dernier tournoi; nil s'il n'y en a pas.
+func loadTournamentResult(path string) (*TournamentResult, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res TournamentResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &res, nil
+}
+
+// runArena joue un tournoi et écrit ses résultats en JSON (et en HTML si demandé).
+// Usage: power4 arena -players easy,medium,hard [-format round-robin|swiss] [-rounds N] [-size 6x7] [-mode normal] [-cadence 1+1]
+func runArena(args []string) error {
+	fset := flag.NewFlagSet("arena", flag.ExitOnError)
+	format := fset.String("format", "round-robin", "format du tournoi: round-robin ou swiss")
+	rounds := fset.Int("rounds", 2, "cycles (toutes rondes) ou rondes (suisse)")
+	size := fset.String("size", "6x7", "taille du plateau (lignes x colonnes)")
+	mode := fset.String("mode", "normal", "mode de gravité")
+	cadence := fset.String("cadence", "", "cadence des parties (ex: 1+1, coup-2)")
+	players := fset.String("players", "easy,medium,hard", "joueurs séparés par des virgules")
+	out := fset.String("out", dataPath(arenaPath), "fichier JSON de sortie")
+	htmlOut := fset.String("html", "", "page HTML de sortie (optionnelle)")
+	fset.Parse(args)
+
+	configs, err := parseSelfPlayConfigs(*size, *mode)
+	if err != nil {
+		return err
+	}
+	cfg := TournamentConfig{
+		Format:  *format,
+		Rounds:  max(*rounds, 1),
+		Rows:    configs[0].Rows,
+		Cols:    configs[0].Cols,
+		Mode:    configs[0].Mode,
+		Cadence: *cadence,
+	}
+	for _, p := range strings.Split(*players, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.Players = append(cfg.Players, p)
+		}
+	}
+	res, err := runTournament(cfg, os.Stdout)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return err
+	}
+	if *htmlOut != "" {
+		// Le template intégré est chargé avant de créer le fichier: pas de rapport vide en cas d'erreur
+		if err := arenaTmpl.load(); err != nil {
+			return err
+		}
+		f, err := os.Create(*htmlOut)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return arenaTmpl.Execute(f, res)
+	}
+	return nil
+}
+
+// arenaHandler affiche les résultats du dernier tournoi (JSON avec ?format=json).
+func arenaHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := loadTournamentResult(dataPath(arenaPath))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	arenaTmpl.Execute(w, res)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRunTournamentRejectsPlayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []string
+	}{
+		{"joueur inscrit deux fois", []string{"easy", "medium", "easy"}},
+		{"joueur inconnu", []string{"easy", "inconnu"}},
+		{"un seul joueur", []string{"easy"}},
+	}
+	for _, tt := range tests {
+		cfg := TournamentConfig{Format: "round-robin", Rounds: 1, Rows: 6, Cols: 7, Mode: "normal", Players: tt.players}
+		if res, err := runTournament(cfg, io.Discard); err == nil {
+			t.Errorf("%s: tournoi joué (%d parties)", tt.name, len(res.Games))
+		}
+	}
+}
+
+func TestRunTournamentRejectsFormat(t *testing.T) {
+	for _, format := range []string{"", "knockout", "Swiss"} {
+		cfg := TournamentConfig{Format: format, Rounds: 1, Rows: 6, Cols: 7, Mode: "normal", Players: []string{"easy", "medium"}}
+		if _, err := runTournament(cfg, io.Discard); err == nil {
+			t.Errorf("format %q accepté", format)
+		}
+	}
+}
+
+func TestRunTournamentRoundRobin(t *testing.T) {
+	cfg := TournamentConfig{Format: "round-robin", Rounds: 1, Rows: 6, Cols: 7, Mode: "normal", Players: []string{"easy", "medium", "hard"}}
+	res, err := runTournament(cfg, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Chaque paire se rencontre deux fois, chacun commençant une fois
+	if len(res.Games) != 6 {
+		t.Fatalf("%d parties, attendu 6", len(res.Games))
+	}
+	total := 0.0
+	for _, s := range res.Standings {
+		if s.Games != 4 || s.First != 2 {
+			t.Errorf("%s: %d parties dont %d en commençant, attendu 4 dont 2", s.Name, s.Games, s.First)
+		}
+		total += s.Score
+	}
+	if total != 6 {
+		t.Errorf("somme des scores %g, attendu 6", total)
+	}
+	if len(res.Pairs) != 3 {
+		t.Errorf("%d paires, attendu 3", len(res.Pairs))
+	}
+	for _, pr := range res.Pairs {
+		if pr.Games != 2 || pr.AFirst != 1 || pr.BFirst != 1 || pr.WinsA+pr.WinsB+pr.Draws != 2 {
+			t.Errorf("paire %s-%s: %+v", pr.A, pr.B, pr)
+		}
+	}
+	for i, row := range res.Crosstable {
+		if len(row) != 3 || row[i] != "x" {
+			t.Errorf("ligne %d du tableau croisé: %v", i, row)
+		}
+	}
+	for i, s := range res.Standings {
+		if s.Rank != i+1 || (i > 0 && s.Score > res.Standings[i-1].Score) {
+			t.Errorf("classement: %+v", res.Standings)
+			break
+		}
+	}
+}
+
+func TestRoundRobinPairings(t *testing.T) {
+	rounds := roundRobinPairings([]string{"a", "b", "c"}, 2)
+	if len(rounds) != 4 {
+		t.Fatalf("%d rondes, attendu 4", len(rounds))
+	}
+	count := map[[2]string]int{}
+	for _, round := range rounds {
+		for _, pair := range round {
+			count[pair]++
+		}
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "a"}, {"a", "c"}, {"c", "a"}, {"b", "c"}, {"c", "b"}} {
+		if count[pair] != 2 {
+			t.Errorf("%s commence %d fois contre %s, attendu 2", pair[0], count[pair], pair[1])
+		}
+	}
+}
+
+func TestSwissPairings(t *testing.T) {
+	players := []string{"a", "b", "c", "d", "e"}
+	score := map[string]float64{"a": 2, "b": 2, "c": 1, "d": 1, "e": 0}
+	firsts := map[string]int{"a": 2, "b": 1}
+	met := map[[2]string]bool{{"a", "b"}: true, {"b", "a"}: true}
+
+	pairs, bye := swissPairings(players, score, firsts, map[string]int{}, met)
+	if bye != "e" {
+		t.Errorf("exemption pour %q, attendu le dernier (e)", bye)
+	}
+	// a et b se sont déjà rencontrés: a affronte le suivant au score, qui commence moins souvent que lui
+	want := [][2]string{{"c", "a"}, {"d", "b"}}
+	if !slices.Equal(pairs, want) {
+		t.Errorf("appariements %v, attendu %v", pairs, want)
+	}
+}
+
+func TestSwissByeRotates(t *testing.T) {
+	players := []string{"a", "b", "c"}
+	score := map[string]float64{"a": 2, "b": 1, "c": 0}
+	// c, dernier, a déjà été exempté: l'exemption passe au moins bien classé des autres
+	_, bye := swissPairings(players, score, map[string]int{}, map[string]int{"c": 1}, map[[2]string]bool{})
+	if bye != "b" {
+		t.Errorf("exemption pour %q, attendu b", bye)
+	}
+	_, bye = swissPairings(players, score, map[string]int{}, map[string]int{"a": 1, "b": 1, "c": 1}, map[[2]string]bool{})
+	if bye != "c" {
+		t.Errorf("exemption pour %q une fois tous exemptés, attendu le dernier (c)", bye)
+	}
+}
+
+func TestRunTournamentSwissByes(t *testing.T) {
+	cfg := TournamentConfig{Format: "swiss", Rounds: 3, Rows: 6, Cols: 7, Mode: "normal", Players: []string{"easy", "medium", "hard"}}
+	res, err := runTournament(cfg, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Games) != 3 {
+		t.Fatalf("%d parties, attendu 3 (une par ronde)", len(res.Games))
+	}
+	total := 0.0
+	for _, s := range res.Standings {
+		// Chacun est exempté une fois: le point d'exemption compte au score, pas dans les parties
+		if s.Byes != 1 || s.Games != 2 {
+			t.Errorf("%s: %d exemptions, %d parties; attendu 1 et 2", s.Name, s.Byes, s.Games)
+		}
+		total += s.Score
+	}
+	if total != 6 {
+		t.Errorf("somme des scores %g, attendu 6 (3 parties et 3 exemptions)", total)
+	}
+}
+
+func TestEstimateElo(t *testing.T) {
+	// A marque 3 points sur 4 contre B, qui fait jeu égal avec C
+	games := [][]int{{0, 4, 0}, {4, 0, 4}, {0, 4, 0}}
+	points := [][]float64{{0, 3, 0}, {1, 0, 2}, {0, 2, 0}}
+	elo, errs := estimateElo(games, points)
+	// Le demi-nul fictif de chaque joueur rapproche un peu B et C de la moyenne
+	if elo[0] <= elo[1] || elo[0] <= elo[2] || math.Abs(elo[1]-elo[2]) > 20 {
+		t.Errorf("Elo %v: attendu A devant, B et C presque à égalité", elo)
+	}
+	if d := elo[0] - elo[1]; d < 100 || d > 250 {
+		t.Errorf("écart A-B %.0f, attendu autour de 190 (score de 75%%)", d)
+	}
+	mean := (elo[0] + elo[1] + elo[2]) / 3
+	if math.Abs(mean-1500) > 50 {
+		t.Errorf("Elo moyen %.0f, attendu autour de 1500", mean)
+	}
+	for i, e := range errs {
+		if e <= 0 {
+			t.Errorf("erreur de %d: %g", i, e)
+		}
+	}
+}
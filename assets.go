@@ -0,0 +1,97 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// embeddedAssets sont les templates et fichiers statiques intégrés au programme.
+//
+//go:embed templates/*.html style.css favicon.svg
+var embeddedAssets embed.FS
+
+// webAssets est la source des templates et fichiers statiques: ceux intégrés au programme,
+// ou en mode développement ceux du répertoire web, relus à chaque modification.
+var (
+	webAssets fs.FS = embeddedAssets
+	devMode   bool
+)
+
+// useWebDir sert les templates et fichiers statiques depuis le disque (mode développement).
+func useWebDir(dir string) {
+	webAssets = os.DirFS(dir)
+	devMode = true
+}
+
+// siteTemplate est un template de page. En mode développement, il est relu dès que son fichier change.
+type siteTemplate struct {
+	name    string // chemin dans webAssets
+	mu      sync.Mutex
+	tmpl    *template.Template
+	modTime time.Time
+}
+
+// load lit le template, avec les fonctions de templateFuncs.
+func (t *siteTemplate) load() error {
+	tmpl, err := template.New(path.Base(t.name)).Funcs(templateFuncs).ParseFS(webAssets, t.name)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.tmpl = tmpl
+	if info, err := fs.Stat(webAssets, t.name); err == nil {
+		t.modTime = info.ModTime()
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// current retourne le template à jour. En mode développement, un fichier modifié est relu;
+// s'il contient une erreur, la version précédente reste en service.
+func (t *siteTemplate) current() *template.Template {
+	t.mu.Lock()
+	tmpl, modTime := t.tmpl, t.modTime
+	t.mu.Unlock()
+	if !devMode && tmpl != nil {
+		return tmpl
+	}
+	if info, err := fs.Stat(webAssets, t.name); err == nil && (tmpl == nil || !info.ModTime().Equal(modTime)) {
+		if err := t.load(); err != nil {
+			// Erreur signalée une seule fois par modification du fichier
+			t.mu.Lock()
+			t.modTime = info.ModTime()
+			t.mu.Unlock()
+			slog.Error("rechargement du template", "template", t.name, "err", err)
+		} else {
+			slog.Debug("template rechargé", "template", t.name)
+		}
+		t.mu.Lock()
+		tmpl = t.tmpl
+		t.mu.Unlock()
+	}
+	return tmpl
+}
+
+// loaded indique si le template a été lu.
+func (t *siteTemplate) loaded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tmpl != nil
+}
+
+// Execute affiche la page avec les données data.
+func (t *siteTemplate) Execute(w io.Writer, data any) error {
+	tmpl := t.current()
+	if tmpl == nil {
+		return fmt.Errorf("template %s indisponible", t.name)
+	}
+	return tmpl.Execute(w, data)
+}
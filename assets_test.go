@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// useAssets sert les templates depuis fsys le temps du test, en mode développement si dev.
+func useAssets(t *testing.T, fsys fs.FS, dev bool) {
+	savedFS, savedDev := webAssets, devMode
+	webAssets, devMode = fsys, dev
+	t.Cleanup(func() { webAssets, devMode = savedFS, savedDev })
+}
+
+// render affiche le template et retourne la page, ou l'erreur.
+func render(t *testing.T, st *siteTemplate) (string, error) {
+	t.Helper()
+	var b strings.Builder
+	err := st.Execute(&b, "monde")
+	return b.String(), err
+}
+
+func TestEmbeddedTemplatesParse(t *testing.T) {
+	useAssets(t, embeddedAssets, false)
+	names, err := fs.Glob(embeddedAssets, "templates/*.html")
+	if err != nil || len(names) == 0 {
+		t.Fatalf("templates intégrés: %v, %v", names, err)
+	}
+	for _, name := range names {
+		if err := (&siteTemplate{name: name}).load(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestSiteTemplateEmbedded(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{"page.html": {Data: []byte("Bonjour {{.}}"), ModTime: start}}
+	useAssets(t, fsys, false)
+	st := &siteTemplate{name: "page.html"}
+	if page, err := render(t, st); err != nil || page != "Bonjour monde" {
+		t.Fatalf("page %q, %v", page, err)
+	}
+	// Hors mode développement, le template lu une fois n'est plus relu
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("Salut {{.}}"), ModTime: start.Add(time.Second)}
+	if page, _ := render(t, st); page != "Bonjour monde" {
+		t.Errorf("template relu hors mode développement: %q", page)
+	}
+
+	if _, err := render(t, &siteTemplate{name: "absente.html"}); err == nil {
+		t.Error("template absent affiché")
+	}
+}
+
+func TestSiteTemplateDevReload(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{"page.html": {Data: []byte("Bonjour {{.}}"), ModTime: start}}
+	useAssets(t, fsys, true)
+	st := &siteTemplate{name: "page.html"}
+	if page, err := render(t, st); err != nil || page != "Bonjour monde" {
+		t.Fatalf("page %q, %v", page, err)
+	}
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("Salut {{.}}"), ModTime: start.Add(time.Second)}
+	if page, _ := render(t, st); page != "Salut monde" {
+		t.Fatalf("fichier modifié non relu: %q", page)
+	}
+
+	// Une erreur de syntaxe laisse la version précédente en service
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("Salut {{."), ModTime: start.Add(2 * time.Second)}
+	if page, err := render(t, st); err != nil || page != "Salut monde" {
+		t.Fatalf("après un template invalide: %q, %v", page, err)
+	}
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("Coucou {{.}}"), ModTime: start.Add(3 * time.Second)}
+	if page, _ := render(t, st); page != "Coucou monde" {
+		t.Errorf("fichier corrigé non relu: %q", page)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BookMove est un coup de la bibliothèque d'ouvertures, tiré au sort selon son poids.
+type BookMove struct {
+	Slot   int `json:"slot"`
+	Weight int `json:"weight"`
+}
+
+// OpeningBook associe, pour chaque taille de plateau ("6x7"), l'empreinte d'une position à ses meilleurs coups.
+type OpeningBook map[string]map[string][]BookMove
+
+// openingBookPath est le fichier chargé au démarrage s'il existe.
+const openingBookPath = "openings.json"
+
+var openingBook OpeningBook
+
+// boardSizeKey retourne la clé de taille de plateau utilisée par la bibliothèque.
+func boardSizeKey(rows, cols int) string {
+	return strconv.Itoa(rows) + "x" + strconv.Itoa(cols)
+}
+
+// positionHash retourne l'empreinte de la position: mode, plateau, gravité et joueur qui a le trait.
+// Dans les modes où la gravité change tous les 5 coups, le moment du cycle en fait partie:
+// deux plateaux identiques n'y ont pas les mêmes suites.
+func (g *Game) positionHash() string {
+	h := fnv.New64a()
+	h.Write([]byte(g.Mode))
+	var buf [3]byte
+	buf[0] = byte(g.Gravity)
+	buf[1] = byte(g.CurrentPlayer)
+	if g.Mode == "inverse" || g.Mode == "rotation" {
+		buf[2] = byte(g.TurnCount%5) + 1
+	}
+	h.Write(buf[:])
+	for _, row := range g.Board {
+		for _, cell := range row {
+			h.Write([]byte{byte(cell)})
+		}
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// loadOpeningBook charge la bibliothèque d'ouvertures; un fichier absent n'est pas une erreur.
+func loadOpeningBook(path string) (OpeningBook, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return OpeningBook{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var book OpeningBook
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return book, nil
+}
+
+// lookup tire au sort un coup de la bibliothèque pour la position courante, s'il en existe un jouable.
+func (b OpeningBook) lookup(g *Game) (int, bool) {
+	moves := b[boardSizeKey(g.Rows, g.Cols)][g.positionHash()]
+	total := 0
+	for _, m := range moves {
+		if _, _, ok := g.landingCell(m.Slot); ok && m.Weight > 0 {
+			total += m.Weight
+		}
+	}
+	if total == 0 {
+		return -1, false
+	}
+	pick := rand.Intn(total)
+	for _, m := range moves {
+		if _, _, ok := g.landingCell(m.Slot); !ok || m.Weight <= 0 {
+			continue
+		}
+		if pick < m.Weight {
+			return m.Slot, true
+		}
+		pick -= m.Weight
+	}
+	return -1, false
+}
+
+// bookEntry construit les coups de bibliothèque d'une position: les meilleurs coups pèsent plus lourd,
+// ceux à moins de margin du meilleur score sont gardés pour varier le jeu.
+func bookEntry(scores []MoveScore, margin int) []BookMove {
+	best := bestSlot(scores)
+	if best < 0 {
+		return nil
+	}
+	bestScore := 0
+	for _, s := range scores {
+		if s.Slot == best {
+			bestScore = s.Score
+		}
+	}
+	var moves []BookMove
+	for _, s := range scores {
+		switch {
+		case s.Score == bestScore:
+			moves = append(moves, BookMove{Slot: s.Slot, Weight: 3})
+		case s.Score >= bestScore-margin && s.Verdict != "perdant":
+			moves = append(moves, BookMove{Slot: s.Slot, Weight: 1})
+		}
+	}
+	return moves
+}
+
+// runBookGenerator construit la bibliothèque d'ouvertures par recherche profonde hors ligne.
+// Usage: power4 book [-plies N] [-depth N] [-time durée] [-sizes 6x7,7x8] [-modes normal,inverse] [-out fichier]
+func runBookGenerator(args []string) error {
+	fset := flag.NewFlagSet("book", flag.ExitOnError)
+	plies := fset.Int("plies", 3, "nombre de demi-coups couverts depuis le plateau vide")
+	depth := fset.Int("depth", 10, "profondeur maximale de recherche")
+	limit := fset.Duration("time", 3*time.Second, "temps de recherche maximal par position")
+	margin := fset.Int("margin", 4, "écart de score toléré pour garder un coup secondaire")
+	sizes := fset.String("sizes", "6x7,7x8,8x10", "tailles de plateau (lignes x colonnes)")
+	modes := fset.String("modes", "normal", "modes de gravité de départ (normal, inverse, gauche, droite, rotation)")
+	out := fset.String("out", dataPath(openingBookPath), "fichier de sortie")
+	fset.Parse(args)
+
+	book, err := loadOpeningBook(*out)
+	if err != nil {
+		return err
+	}
+	for _, size := range strings.Split(*sizes, ",") {
+		rowsStr, colsStr, _ := strings.Cut(strings.TrimSpace(size), "x")
+		rows, err1 := strconv.Atoi(rowsStr)
+		cols, err2 := strconv.Atoi(colsStr)
+		if err1 != nil || err2 != nil || rows < 4 || cols < 4 {
+			return fmt.Errorf("taille invalide: %q", size)
+		}
+		key := boardSizeKey(rows, cols)
+		if book[key] == nil {
+			book[key] = map[string][]BookMove{}
+		}
+		for _, mode := range strings.Split(*modes, ",") {
+			for starter := 1; starter <= 2; starter++ {
+				root := NewGame(rows, cols, 0, "", "", "", strings.TrimSpace(mode), "", ModeHumanVsHuman, AIEasy)
+				root.CurrentPlayer = starter
+				n := generateBookPositions(book[key], root, *plies, *depth, *limit, *margin)
+				fmt.Printf("%s %s (joueur %d au trait): %d positions\n", key, mode, starter, n)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(book, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
+
+// generateBookPositions parcourt en largeur les positions jusqu'à plies demi-coups et analyse chacune d'elles.
+func generateBookPositions(entries map[string][]BookMove, root *Game, plies, depth int, limit time.Duration, margin int) int {
+	seen := map[string]bool{}
+	level := []*Game{root}
+	count := 0
+	for ply := 0; ply < plies && len(level) > 0; ply++ {
+		var next []*Game
+		for _, pos := range level {
+			hash := pos.positionHash()
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			if _, ok := entries[hash]; !ok {
+				scores, _ := analyseMoves(pos, depth, limit)
+				if moves := bookEntry(scores, margin); len(moves) > 0 {
+					entries[hash] = moves
+					count++
+				}
+			}
+			for _, slot := range pos.getValidMoves() {
+				child := pos.clone()
+				if child.DropToken(slot) && !child.GameOver {
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+	return count
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPositionHash(t *testing.T) {
+	a, b := newTestGame("normal"), newTestGame("normal")
+	if a.positionHash() != b.positionHash() {
+		t.Fatal("deux positions identiques n'ont pas la même empreinte")
+	}
+	// Les modes sans changement de gravité ignorent le nombre de coups joués
+	b.TurnCount = 3
+	if a.positionHash() != b.positionHash() {
+		t.Error("mode normal: l'empreinte dépend du nombre de coups")
+	}
+
+	base := newTestGame("rotation")
+	tests := []struct {
+		name   string
+		change func(g *Game)
+	}{
+		{"joueur au trait", func(g *Game) { g.CurrentPlayer = 2 }},
+		{"gravité", func(g *Game) { g.Gravity = GravityLeft }},
+		{"plateau", func(g *Game) { g.Board[5][3] = 1 }},
+		{"mode", func(g *Game) { g.Mode = "inverse" }},
+		{"moment du cycle", func(g *Game) { g.TurnCount = 4 }},
+	}
+	for _, tt := range tests {
+		g := newTestGame("rotation")
+		tt.change(g)
+		if g.positionHash() == base.positionHash() {
+			t.Errorf("%s: même empreinte", tt.name)
+		}
+	}
+	// Même moment du cycle un tour de gravité plus tard
+	g := newTestGame("rotation")
+	g.TurnCount = 5
+	if g.positionHash() != base.positionHash() {
+		t.Error("rotation: l'empreinte dépend d'autre chose que du moment du cycle")
+	}
+}
+
+func TestOpeningBookLookup(t *testing.T) {
+	g := newTestGame("normal")
+	for r := range g.Board {
+		g.Board[r][0] = 1 + r%2 // colonne 0 pleine
+	}
+	book := OpeningBook{boardSizeKey(g.Rows, g.Cols): {
+		g.positionHash(): {{Slot: 0, Weight: 5}, {Slot: 3, Weight: 2}, {Slot: 4, Weight: 0}},
+	}}
+	for range 20 {
+		if slot, ok := book.lookup(g); !ok || slot != 3 {
+			t.Fatalf("lookup = %d, %v; attendu le seul coup jouable de poids non nul (3)", slot, ok)
+		}
+	}
+
+	g.DropToken(3)
+	if slot, ok := book.lookup(g); ok {
+		t.Errorf("position absente de la bibliothèque: coup %d proposé", slot)
+	}
+	g.Rows, g.Cols = 7, 8
+	if _, ok := book.lookup(g); ok {
+		t.Error("coup proposé pour une autre taille de plateau")
+	}
+}
+
+func TestBookEntry(t *testing.T) {
+	scores := []MoveScore{
+		{Slot: 0, Score: -50, Verdict: "perdant"},
+		{Slot: 1, Score: 8, Verdict: "incertain"},
+		{Slot: 2, Score: 10, Verdict: "incertain"},
+		{Slot: 3, Score: 10, Verdict: "incertain"},
+		{Slot: 4, Score: 2, Verdict: "incertain"},
+	}
+	want := []BookMove{{1, 1}, {2, 3}, {3, 3}}
+	if got := bookEntry(scores, 4); !slices.Equal(got, want) {
+		t.Errorf("bookEntry = %v, attendu %v", got, want)
+	}
+	if got := bookEntry(nil, 4); got != nil {
+		t.Errorf("bookEntry sans coup = %v", got)
+	}
+}
+
+func TestLoadOpeningBookMissing(t *testing.T) {
+	book, err := loadOpeningBook(filepath.Join(t.TempDir(), "absent.json"))
+	if err != nil || book == nil || len(book) != 0 {
+		t.Fatalf("fichier absent: %v, %v", book, err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	chatPlayers     = 0   // canal des joueurs
+	chatSpectators  = 1   // canal des spectateurs
+	chatMaxLength   = 200 // longueur maximale d'un message, en caractères
+	chatHistorySize = 200 // messages conservés par canal
+)
+
+// ChatMessage est un message de la discussion d'une partie. Le texte est conservé tel quel
+// et échappé à l'affichage (template et textContent côté navigateur).
+type ChatMessage struct {
+	ID       int       `json:"id"` // numéro croissant du message dans son canal
+	Author   string    `json:"author"`
+	Seat     int       `json:"seat"` // place de l'auteur (0 pour un spectateur)
+	Text     string    `json:"text"`
+	Reaction bool      `json:"reaction"`
+	At       time.Time `json:"at"`
+}
+
+// Reaction est un message prédéfini envoyé en un clic.
+type Reaction struct {
+	Key   string
+	Label string
+}
+
+// reactions sont les réactions rapides proposées sous la discussion.
+var reactions = []Reaction{
+	{"gg", "GG"},
+	{"bien-joue", "Bien joué !"},
+	{"bonne-chance", "Bonne chance !"},
+	{"oups", "Oups"},
+	{"merci", "Merci"},
+	{"waouh", "Waouh !"},
+}
+
+// reactionLabel retourne le texte d'une réaction rapide, ou ok=false si elle est inconnue.
+func reactionLabel(key string) (string, bool) {
+	for _, r := range reactions {
+		if r.Key == key {
+			return r.Label, true
+		}
+	}
+	return "", false
+}
+
+// cleanChatText retire les caractères de contrôle et les espaces superflus d'un message.
+func cleanChatText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// chatChannel retourne le canal du visiteur: celui des joueurs s'il participe à la partie, sinon celui des spectateurs.
+// Appelé avec s.mu verrouillé.
+func (s *GameSession) chatChannel(p *Player) int {
+	if s.seated(p) {
+		return chatPlayers
+	}
+	return chatSpectators
+}
+
+// addChat numérote le message, l'ajoute au canal et prévient les pages abonnées. Appelé avec s.mu verrouillé.
+func (s *GameSession) addChat(channel int, msg ChatMessage) ChatMessage {
+	g := s.Game
+	g.ChatSeq[channel]++
+	msg.ID = g.ChatSeq[channel]
+	g.Chat[channel] = append(g.Chat[channel], msg)
+	if n := len(g.Chat[channel]); n > chatHistorySize {
+		g.Chat[channel] = append([]ChatMessage(nil), g.Chat[channel][n-chatHistorySize:]...)
+	}
+	s.broadcast()
+	return msg
+}
+
+// chatHandler enregistre un message ou une réaction rapide dans le canal du visiteur.
+func chatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	sess := lookupSession(r.URL.Query().Get("id"))
+	if sess == nil {
+		http.Error(w, "Partie introuvable", http.StatusNotFound)
+		return
+	}
+	player := currentPlayer(w, r)
+	if !checkCSRF(r, player) {
+		http.Error(w, errNoCSRF.Error(), http.StatusForbidden)
+		return
+	}
+
+	msg := ChatMessage{At: time.Now()}
+	if key := r.FormValue("reaction"); key != "" {
+		label, ok := reactionLabel(key)
+		if !ok {
+			http.Error(w, "Réaction inconnue", http.StatusBadRequest)
+			return
+		}
+		msg.Text, msg.Reaction = label, true
+	} else {
+		msg.Text = cleanChatText(r.FormValue("text"))
+		if msg.Text == "" {
+			http.Error(w, "Message vide", http.StatusBadRequest)
+			return
+		}
+		if len([]rune(msg.Text)) > chatMaxLength {
+			http.Error(w, "Message trop long", http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.Game == nil {
+		http.Error(w, "Aucune partie en cours", http.StatusNotFound)
+		return
+	}
+	channel := sess.chatChannel(player)
+	mutex.Lock()
+	msg.Author = player.Name
+	if sess.Room != nil {
+		msg.Seat = sess.Room.seatOf(player)
+	}
+	mutex.Unlock()
+	if msg.Author == "" {
+		msg.Author = "Spectateur"
+	}
+	msg = sess.addChat(channel, msg)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
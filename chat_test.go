@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCleanChatText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"bonjour", "bonjour"},
+		{"  bien\tjoué  ", "bien joué"},
+		{"a\nb\x00c", "a b c"},
+		{" \r\n ", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanChatText(tt.in); got != tt.want {
+			t.Errorf("cleanChatText(%q) = %q, attendu %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// postChat envoie un message par chatHandler au nom du joueur p.
+func postChat(t *testing.T, sess *GameSession, p *Player, text string) ChatMessage {
+	t.Helper()
+	r := postForm(url.Values{"text": {text}, "csrf": {p.csrfToken()}})
+	r.URL.RawQuery = "id=" + sess.ID
+	r.AddCookie(&http.Cookie{Name: playerCookie, Value: p.ID})
+	w := httptest.NewRecorder()
+	chatHandler(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("message %q: statut %d", text, w.Code)
+	}
+	var msg ChatMessage
+	if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestChatDeliveredPastHistorySize(t *testing.T) {
+	sess := newSession(nil)
+	sess.Game = newTestGame("normal")
+	p := &Player{ID: newID(), Name: "Zoé", Rating: defaultRating}
+	mutex.Lock()
+	players[p.ID] = p
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		delete(players, p.ID)
+		mutex.Unlock()
+	})
+
+	// L'historique du canal est plein: seuls les derniers messages sont conservés
+	var last ChatMessage
+	for i := range chatHistorySize + 10 {
+		last = postChat(t, sess, p, fmt.Sprintf("message %d", i))
+	}
+	if last.ID != chatHistorySize+10 {
+		t.Fatalf("numéro du dernier message: %d, attendu %d", last.ID, chatHistorySize+10)
+	}
+	if n := len(sess.Game.Chat[chatSpectators]); n != chatHistorySize {
+		t.Fatalf("%d messages conservés, attendu %d", n, chatHistorySize)
+	}
+
+	events := openEvents(t, sess, p, last.ID)
+
+	// Les messages suivants arrivent encore, chacun une seule fois
+	for i := range 3 {
+		sent := postChat(t, sess, p, fmt.Sprintf("suite %d", i))
+		if msg := nextChat(t, events); msg.ID != sent.ID || msg.Text != sent.Text {
+			t.Fatalf("reçu %d %q, attendu %d %q", msg.ID, msg.Text, sent.ID, sent.Text)
+		}
+	}
+}
+
+// nextChat attend le prochain message de discussion du flux, en sautant les états de la partie.
+func nextChat(t *testing.T, events <-chan sseEvent) ChatMessage {
+	t.Helper()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("flux fermé")
+			}
+			if ev.Name != "chat" {
+				continue
+			}
+			var msg ChatMessage
+			if err := json.Unmarshal([]byte(ev.Data), &msg); err != nil {
+				t.Fatal(err)
+			}
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Fatal("aucun message reçu")
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeControl décrit la cadence d'une partie: temps total + incrément, ou temps fixe par coup.
+type TimeControl struct {
+	Name           string        // cadence telle que saisie ("3+2", "coup-10", "jours-3", ...)
+	Base           time.Duration // temps total par joueur
+	Increment      time.Duration // temps ajouté après chaque coup
+	PerMove        time.Duration // temps fixe par coup (remplace Base/Increment)
+	Correspondence bool          // partie par correspondance: des jours par coup, dépassement = forfait
+}
+
+// Enabled indique si la partie est jouée à la pendule.
+func (tc TimeControl) Enabled() bool {
+	return tc.Base > 0 || tc.PerMove > 0
+}
+
+// parseTimeControl lit une cadence "minutes+secondes" (ex: "3+2"), "coup-N" (N secondes par coup)
+// ou "jours-N" (N jours par coup, par correspondance). Une cadence vide ou invalide désactive la pendule.
+func parseTimeControl(s string) TimeControl {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return TimeControl{}
+	}
+	if secs, ok := strings.CutPrefix(s, "coup-"); ok {
+		n, err := strconv.Atoi(secs)
+		if err != nil || n <= 0 {
+			return TimeControl{}
+		}
+		return TimeControl{Name: s, PerMove: time.Duration(n) * time.Second}
+	}
+	if days, ok := strings.CutPrefix(s, "jours-"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 || n > 30 {
+			return TimeControl{}
+		}
+		return TimeControl{Name: s, PerMove: time.Duration(n) * 24 * time.Hour, Correspondence: true}
+	}
+	minutes, inc, _ := strings.Cut(s, "+")
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m <= 0 {
+		return TimeControl{}
+	}
+	i := 0
+	if inc != "" {
+		i, err = strconv.Atoi(inc)
+		if err != nil || i < 0 {
+			return TimeControl{}
+		}
+	}
+	return TimeControl{Name: s, Base: time.Duration(m) * time.Minute, Increment: time.Duration(i) * time.Second}
+}
+
+// SetTimeControl active la pendule et lance le temps du joueur qui a le trait.
+func (g *Game) SetTimeControl(tc TimeControl) {
+	g.TimeControl = tc
+	if !tc.Enabled() {
+		return
+	}
+	start := tc.Base
+	if tc.PerMove > 0 {
+		start = tc.PerMove
+	}
+	g.Remaining = [2]time.Duration{start, start}
+	g.TurnStart = time.Now()
+}
+
+// TimeLeft retourne le temps restant du joueur (1 ou 2), en tenant compte du temps écoulé s'il a le trait.
+func (g *Game) TimeLeft(player int) time.Duration {
+	if !g.TimeControl.Enabled() || player < 1 || player > 2 {
+		return 0
+	}
+	left := g.Remaining[player-1]
+	if player == g.CurrentPlayer && !g.GameOver {
+		left -= time.Since(g.TurnStart)
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// checkTimeout termine la partie si le joueur qui a le trait a épuisé son temps
+// (par forfait dans une partie par correspondance).
+func (g *Game) checkTimeout() bool {
+	if g == nil || g.GameOver || !g.TimeControl.Enabled() {
+		return false
+	}
+	if g.TimeLeft(g.CurrentPlayer) > 0 {
+		return false
+	}
+	g.Remaining[g.CurrentPlayer-1] = 0
+	g.TimedOut = true
+	g.Forfeited = g.TimeControl.Correspondence
+	g.finish(3 - g.CurrentPlayer)
+	return true
+}
+
+// punchClock décompte le temps du coup qui vient d'être joué et relance la pendule de l'adversaire.
+func (g *Game) punchClock(player int) {
+	if !g.TimeControl.Enabled() {
+		return
+	}
+	now := time.Now()
+	if g.TimeControl.PerMove > 0 {
+		g.Remaining[player-1] = g.TimeControl.PerMove
+	} else {
+		g.Remaining[player-1] -= now.Sub(g.TurnStart)
+		g.Remaining[player-1] += g.TimeControl.Increment
+	}
+	g.TurnStart = now
+}
+
+// aiDeadline retourne l'heure limite de réflexion de l'IA d'après sa pendule (zéro si pas de pendule).
+func (g *Game) aiDeadline() time.Time {
+	if !g.TimeControl.Enabled() {
+		return time.Time{}
+	}
+	left := g.TimeLeft(g.CurrentPlayer)
+	var budget time.Duration
+	if g.TimeControl.PerMove > 0 {
+		budget = left * 8 / 10
+	} else {
+		// On garde une marge: une fraction du temps restant plus une partie de l'incrément
+		budget = left/20 + g.TimeControl.Increment/2
+		if budget > left/2 {
+			budget = left / 2
+		}
+	}
+	return time.Now().Add(budget)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TimeControl
+	}{
+		{"", TimeControl{}},
+		{"3+2", TimeControl{Name: "3+2", Base: 3 * time.Minute, Increment: 2 * time.Second}},
+		{" 5 ", TimeControl{Name: "5", Base: 5 * time.Minute}},
+		{"1+0", TimeControl{Name: "1+0", Base: time.Minute}},
+		{"coup-10", TimeControl{Name: "coup-10", PerMove: 10 * time.Second}},
+		{"jours-3", TimeControl{Name: "jours-3", PerMove: 3 * 24 * time.Hour, Correspondence: true}},
+		{"0+5", TimeControl{}},
+		{"3+-1", TimeControl{}},
+		{"3+x", TimeControl{}},
+		{"abc", TimeControl{}},
+		{"coup-0", TimeControl{}},
+		{"coup-x", TimeControl{}},
+		{"jours-31", TimeControl{}},
+		{"jours-0", TimeControl{}},
+	}
+	for _, tt := range tests {
+		got := parseTimeControl(tt.in)
+		if got != tt.want {
+			t.Errorf("parseTimeControl(%q) = %+v, attendu %+v", tt.in, got, tt.want)
+		}
+		if got.Enabled() != (tt.want.Name != "") {
+			t.Errorf("parseTimeControl(%q).Enabled() = %v", tt.in, got.Enabled())
+		}
+	}
+}
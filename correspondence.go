@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	notifiersPath   = "notifiers.json"
+	sweepInterval   = time.Minute
+	reminderBefore  = 12 * time.Hour // rappel envoyé quand il reste moins que cela pour jouer
+	notifierTimeout = 5 * time.Second
+)
+
+// Notification est un message adressé à un joueur d'une partie par correspondance.
+type Notification struct {
+	Event    string    `json:"event"` // "debut", "a-vous", "rappel" ou "fin"
+	GameID   string    `json:"game_id"`
+	Player   string    `json:"player"`
+	Email    string    `json:"email,omitempty"`
+	Opponent string    `json:"opponent"`
+	Subject  string    `json:"subject"`
+	Text     string    `json:"text"`
+	URL      string    `json:"url"`
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// Notifier envoie les notifications des parties par correspondance.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// webhookNotifier envoie chaque notification en JSON (POST) à une URL.
+type webhookNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+func (wn *webhookNotifier) Notify(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	resp, err := wn.client.Post(wn.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: statut HTTP %d", wn.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// mailDropNotifier ajoute chaque notification sous forme de courriel à un fichier local (format mbox),
+// qu'un agent de messagerie peut relever.
+type mailDropNotifier struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (md *mailDropNotifier) Notify(n Notification) error {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	f, err := os.OpenFile(md.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Sans adresse, le pseudo est encodé (RFC 2047): un saut de ligne ne peut pas ajouter d'en-tête
+	to := n.Email
+	if to == "" {
+		to = mime.QEncoding.Encode("utf-8", n.Player)
+	}
+	now := time.Now()
+	// Les lignes commençant par "From " sont échappées, comme le veut le format mbox
+	text := strings.ReplaceAll("\n"+n.Text+"\n"+n.URL, "\nFrom ", "\n>From ")
+	_, err = fmt.Fprintf(f, "From %s %s\nFrom: %s\nTo: %s\nDate: %s\nSubject: %s\nContent-Type: text/plain; charset=utf-8\n%s\n\n",
+		md.From, now.Format(time.ANSIC), md.From, to, now.Format(time.RFC1123Z), mime.QEncoding.Encode("utf-8", n.Subject), text)
+	return err
+}
+
+// NotifierConfig décrit un notificateur dans notifiers.json.
+type NotifierConfig struct {
+	Type string `json:"type"` // "webhook" ou "maildrop"
+	URL  string `json:"url"`  // webhook
+	Path string `json:"path"` // maildrop
+	From string `json:"from"` // maildrop (expéditeur)
+}
+
+// notifiers reçoivent toutes les notifications (aucun par défaut).
+var notifiers []Notifier
+
+// loadNotifiers lit la liste des notificateurs; un fichier absent n'est pas une erreur.
+func loadNotifiers(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []NotifierConfig
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, cfg := range list {
+		switch cfg.Type {
+		case "webhook":
+			if cfg.URL == "" {
+				return fmt.Errorf("%s: webhook sans URL", path)
+			}
+			notifiers = append(notifiers, &webhookNotifier{URL: cfg.URL, client: &http.Client{Timeout: notifierTimeout}})
+		case "maildrop":
+			if cfg.Path == "" {
+				return fmt.Errorf("%s: maildrop sans fichier", path)
+			}
+			if cfg.From == "" {
+				cfg.From = "power4@localhost"
+			}
+			notifiers = append(notifiers, &mailDropNotifier{Path: cfg.Path, From: cfg.From})
+		default:
+			return fmt.Errorf("%s: type de notificateur inconnu %q", path, cfg.Type)
+		}
+	}
+	return nil
+}
+
+// notify envoie la notification à tous les notificateurs, sans bloquer l'appelant.
+func notify(n Notification) {
+	for _, nt := range notifiers {
+		go func(nt Notifier) {
+			if err := nt.Notify(n); err != nil {
+				slog.Error("notification", "game", n.GameID, "event", n.Event, "err", err)
+			}
+		}(nt)
+	}
+}
+
+// baseURL est l'adresse publique du serveur, utilisée dans les liens des notifications.
+var baseURL = "http://localhost:8081"
+
+// turnKey identifie un tour de jeu: une partie et le nombre de coups joués.
+type turnKey struct {
+	game  *Game
+	moves int
+}
+
+// turn retourne le tour en cours. Appelé avec s.mu verrouillé.
+func (s *GameSession) turn() turnKey {
+	if s.Game == nil {
+		return turnKey{}
+	}
+	return turnKey{s.Game, len(s.Game.History)}
+}
+
+// notification prépare un message pour la place seat. Appelé avec s.mu verrouillé.
+func (s *GameSession) notification(seat int, event, subject, text string) Notification {
+	mutex.Lock()
+	p, opp := s.Room.Seats[seat-1], s.Room.Seats[2-seat]
+	n := Notification{
+		Event:   event,
+		GameID:  s.ID,
+		Player:  p.Name,
+		Email:   p.Email,
+		Subject: subject,
+		Text:    text,
+		URL:     baseURL + "/room?id=" + s.ID,
+	}
+	if opp != nil {
+		n.Opponent = opp.Name
+	}
+	mutex.Unlock()
+	if g := s.Game; g != nil && !g.GameOver && g.CurrentPlayer == seat {
+		n.Deadline = time.Now().Add(g.TimeLeft(seat))
+	}
+	return n
+}
+
+// correspondenceUpdate prévient les joueurs d'une partie par correspondance: début, tour de jeu et fin.
+// Appelé avec s.mu verrouillé, après chaque changement.
+func (s *GameSession) correspondenceUpdate() {
+	if !s.persistent() || s.Room.waiting() || s.Game == nil {
+		return
+	}
+	g := s.Game
+	if g.GameOver {
+		if s.notifiedOver == g {
+			return
+		}
+		s.notifiedOver = g
+		for seat := 1; seat <= 2; seat++ {
+			notify(s.notification(seat, "fin", "Partie terminée", endMessage(g, seat)))
+		}
+		return
+	}
+	if s.notifiedTurn == s.turn() {
+		return
+	}
+	s.notifiedTurn = s.turn()
+	if len(g.History) == 0 {
+		notify(s.notification(3-g.CurrentPlayer, "debut", "Nouvelle partie par correspondance",
+			"La partie a commencé, votre adversaire a le trait."))
+	}
+	notify(s.notification(g.CurrentPlayer, "a-vous", "À vous de jouer",
+		fmt.Sprintf("C'est votre tour. Vous avez %s pour jouer.", formatDeadline(g.TimeLeft(g.CurrentPlayer)))))
+}
+
+// formatDeadline affiche une durée en jours et heures.
+func formatDeadline(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	if days == 0 {
+		return fmt.Sprintf("%d h %02d", hours, int(d%time.Hour/time.Minute))
+	}
+	return fmt.Sprintf("%d j %d h", days, hours)
+}
+
+// sweep termine par forfait les parties par correspondance dont le délai est dépassé
+// et envoie un rappel aux joueurs dont le délai approche.
+func sweep() {
+	mutex.Lock()
+	var list []*GameSession
+	for _, s := range sessions {
+		if s.persistent() {
+			list = append(list, s)
+		}
+	}
+	mutex.Unlock()
+
+	for _, s := range list {
+		s.mu.Lock()
+		g := s.Game
+		switch {
+		case g == nil || g.GameOver || s.Room.waiting():
+		case g.checkTimeout():
+			slog.Info("partie par correspondance perdue au temps", "game", s.ID, "loser", g.playerName(3-g.Winner))
+			s.touch(context.Background())
+			s.settle()
+		case g.TimeLeft(g.CurrentPlayer) < reminderBefore && s.remindedTurn != s.turn():
+			s.remindedTurn = s.turn()
+			notify(s.notification(g.CurrentPlayer, "rappel", "Rappel: à vous de jouer",
+				fmt.Sprintf("Il vous reste %s pour jouer, faute de quoi la partie sera perdue par forfait.", formatDeadline(g.TimeLeft(g.CurrentPlayer)))))
+		}
+		s.mu.Unlock()
+	}
+}
+
+// runSweeper lance le balayage périodique des parties par correspondance.
+func runSweeper(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			sweep()
+		}
+	}()
+}
+
+// MyGameEntry est une partie en salle du visiteur.
+type MyGameEntry struct {
+	ID       string
+	Opponent string
+	Cadence  string
+	Moves    int
+	YourTurn bool
+	Waiting  bool // en attente d'un adversaire
+	Over     bool
+	Result   string
+	TimeLeft string // temps restant du joueur qui a le trait
+}
+
+// myGamesHandler liste les parties en salle du visiteur: d'abord celles où il a le trait.
+func myGamesHandler(w http.ResponseWriter, r *http.Request) {
+	player := currentPlayer(w, r)
+	mutex.Lock()
+	var list []*GameSession
+	for _, s := range sessions {
+		if s.Room != nil && s.Room.seatOf(player) != 0 {
+			list = append(list, s)
+		}
+	}
+	mutex.Unlock()
+
+	var entries []MyGameEntry
+	for _, s := range list {
+		s.mu.Lock()
+		g, room := s.Game, s.Room
+		mutex.Lock()
+		seat := room.seatOf(player)
+		e := MyGameEntry{ID: s.ID, Cadence: room.Cadence, Waiting: room.waiting()}
+		if opp := room.Seats[2-seat]; opp != nil {
+			e.Opponent = opp.Name
+		}
+		mutex.Unlock()
+		if g != nil {
+			if g.checkTimeout() {
+				s.touch(r.Context())
+				s.settle()
+			}
+			e.Moves = len(g.History)
+			e.Over = g.GameOver
+			e.YourTurn = !e.Waiting && !g.GameOver && g.CurrentPlayer == seat
+			e.Result = endMessage(g, seat)
+			if !e.Waiting && !g.GameOver && g.TimeControl.Enabled() {
+				e.TimeLeft = formatDeadline(g.TimeLeft(g.CurrentPlayer))
+			}
+		}
+		s.mu.Unlock()
+		entries = append(entries, e)
+	}
+	rank := func(e MyGameEntry) int {
+		switch {
+		case e.YourTurn:
+			return 0
+		case e.Over:
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if rank(entries[i]) != rank(entries[j]) {
+			return rank(entries[i]) < rank(entries[j])
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	yourTurn := 0
+	for _, e := range entries {
+		if e.YourTurn {
+			yourTurn++
+		}
+	}
+	myGamesTmpl.Execute(w, struct {
+		Games    []MyGameEntry
+		YourTurn int
+	}{entries, yourTurn})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordNotifier transmet les notifications reçues au test.
+type recordNotifier struct {
+	ch chan Notification
+}
+
+func (rn *recordNotifier) Notify(n Notification) error {
+	rn.ch <- n
+	return nil
+}
+
+// useRecordNotifier remplace les notificateurs le temps du test.
+func useRecordNotifier(t *testing.T) *recordNotifier {
+	rn := &recordNotifier{ch: make(chan Notification, 16)}
+	saved := notifiers
+	notifiers = []Notifier{rn}
+	t.Cleanup(func() { notifiers = saved })
+	return rn
+}
+
+// expect attend les notifications annoncées, dans n'importe quel ordre, "événement pseudo" chacune.
+func (rn *recordNotifier) expect(t *testing.T, want ...string) {
+	t.Helper()
+	pending := map[string]int{}
+	for _, w := range want {
+		pending[w]++
+	}
+	for range want {
+		select {
+		case n := <-rn.ch:
+			key := n.Event + " " + n.Player
+			if pending[key] == 0 {
+				t.Errorf("notification inattendue: %s (%q)", key, n.Subject)
+				continue
+			}
+			pending[key]--
+		case <-time.After(2 * time.Second):
+			t.Fatalf("notifications manquantes parmi %v", want)
+		}
+	}
+	select {
+	case n := <-rn.ch:
+		t.Errorf("notification en trop: %s %s", n.Event, n.Player)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// newCorrespondenceSession ouvre une partie par correspondance entre Alice (place 1) et Bob.
+func newCorrespondenceSession(t *testing.T) *GameSession {
+	alice := &Player{ID: newID(), Name: "Alice", Rating: defaultRating}
+	bob := &Player{ID: newID(), Name: "Bob", Rating: defaultRating}
+	room := &Room{
+		RoomSettings: RoomSettings{Difficulty: "easy", Mode: "normal", Cadence: "jours-3", BestOf: 1},
+		Seats:        [2]*Player{alice, bob},
+		Created:      time.Now(),
+	}
+	sess := newSession(room)
+	sess.Game = newRoomGame(room, nil)
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		mutex.Unlock()
+	})
+	return sess
+}
+
+func TestCorrespondenceNotifications(t *testing.T) {
+	rn := useRecordNotifier(t)
+	sess := newCorrespondenceSession(t)
+	ctx := context.Background()
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if !sess.persistent() {
+		t.Fatal("partie par correspondance non enregistrée")
+	}
+
+	sess.touch(ctx)
+	rn.expect(t, "debut Bob", "a-vous Alice")
+	// Un changement sans coup ne renvoie rien
+	sess.touch(ctx)
+	rn.expect(t)
+
+	sess.Game.DropToken(3)
+	sess.touch(ctx)
+	rn.expect(t, "a-vous Bob")
+
+	sess.Game.finish(1)
+	sess.touch(ctx)
+	rn.expect(t, "fin Alice", "fin Bob")
+	sess.touch(ctx)
+	rn.expect(t)
+}
+
+func TestSweep(t *testing.T) {
+	rn := useRecordNotifier(t)
+	sess := newCorrespondenceSession(t)
+	sess.mu.Lock()
+	sess.notifiedTurn = sess.turn() // début déjà annoncé
+	g := sess.Game
+	g.DropToken(3)
+	sess.notifiedTurn = sess.turn()
+	// Bob a le trait et il lui reste moins que le délai de rappel
+	g.TurnStart = time.Now().Add(-g.TimeControl.PerMove + reminderBefore/2)
+	sess.mu.Unlock()
+
+	sweep()
+	rn.expect(t, "rappel Bob")
+	sweep()
+	rn.expect(t) // un seul rappel par tour
+
+	// Délai dépassé: forfait et classement mis à jour
+	sess.mu.Lock()
+	g.TurnStart = time.Now().Add(-g.TimeControl.PerMove - time.Minute)
+	sess.mu.Unlock()
+	sweep()
+	rn.expect(t, "fin Alice", "fin Bob")
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if !g.GameOver || g.Winner != 1 || !g.Forfeited {
+		t.Fatalf("partie après le délai: GameOver=%v Winner=%d Forfeited=%v", g.GameOver, g.Winner, g.Forfeited)
+	}
+	if a, b := sess.Room.Seats[0].Rating, sess.Room.Seats[1].Rating; !sess.Room.Rated || a <= b || a+b != 2*defaultRating {
+		t.Errorf("classement après forfait: %d et %d", a, b)
+	}
+}
+
+func TestMailDropNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "power4.mbox")
+	md := &mailDropNotifier{Path: path, From: "power4@example.org"}
+	err := md.Notify(Notification{
+		Player:  "Mallory\nBcc: victime@example.org",
+		Subject: "À vous de jouer",
+		Text:    "Bonjour\nFrom la partie",
+		URL:     "http://localhost:8081/room?id=abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := string(data)
+	for _, line := range strings.Split(mail, "\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Errorf("en-tête ajouté par le pseudo: %q", line)
+		}
+	}
+	if !strings.Contains(mail, "\n>From la partie") || strings.Count(mail, "\nFrom ") != 0 {
+		t.Errorf("ligne \"From \" non échappée:\n%s", mail)
+	}
+	if !strings.Contains(mail, "Subject: =?utf-8?q?") {
+		t.Errorf("sujet non encodé:\n%s", mail)
+	}
+}
+
+func TestFormatDeadline(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{90 * time.Minute, "1 h 30"},
+		{5 * time.Minute, "0 h 05"},
+		{50 * time.Hour, "2 j 2 h"},
+	}
+	for _, tt := range tests {
+		if got := formatDeadline(tt.d); got != tt.want {
+			t.Errorf("formatDeadline(%v) = %q, attendu %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// Champs de formulaire des jetons: jeton anti-CSRF du visiteur et jeton de la place qui joue le coup.
+const (
+	csrfField      = "csrf"
+	csrfHeader     = "X-CSRF-Token"
+	seatTokenField = "seat"
+)
+
+// errNoCSRF est le détail affiché quand un formulaire arrive sans jeton valide (page d'un autre site,
+// ou page ouverte avant un redémarrage du serveur).
+var errNoCSRF = errors.New("jeton de formulaire absent ou expiré: rechargez la page puis recommencez")
+
+// csrfToken retourne le jeton anti-CSRF du joueur, créé à la première demande. Chaque formulaire
+// qui modifie une partie le renvoie; une page d'un autre site ne peut pas le connaître.
+func (p *Player) csrfToken() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if p.CSRF == "" {
+		p.CSRF = newID()
+	}
+	return p.CSRF
+}
+
+// checkCSRF vérifie le jeton anti-CSRF d'une requête POST: champ csrf ou en-tête X-CSRF-Token.
+func checkCSRF(r *http.Request, p *Player) bool {
+	token := r.PostFormValue(csrfField)
+	if token == "" {
+		token = r.Header.Get(csrfHeader)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.csrfToken())) == 1
+}
+
+// rejectCSRF répond à une requête dont le jeton anti-CSRF est absent ou périmé.
+func rejectCSRF(w http.ResponseWriter, r *http.Request) {
+	logger(r.Context()).Warn("jeton anti-CSRF refusé", "path", r.URL.Path, "remote", r.RemoteAddr)
+	renderError(w, http.StatusForbidden, "Requête refusée", errNoCSRF, appPath(r.URL.RequestURI()))
+}
+
+// assignSeatTokens attribue un jeton à chaque place de la partie (aux parties enregistrées qui n'en ont pas).
+func (g *Game) assignSeatTokens() {
+	for i := range g.SeatTokens {
+		if g.SeatTokens[i] == "" {
+			g.SeatTokens[i] = newID()
+		}
+	}
+}
+
+// seatToken retourne le jeton de la place seat (1 ou 2), remis au seul joueur qui l'occupe.
+func (g *Game) seatToken(seat int) string {
+	if seat < 1 || seat > 2 {
+		return ""
+	}
+	return g.SeatTokens[seat-1]
+}
+
+// tokenSeat retourne la place dont token est le jeton, 0 si aucune.
+func (g *Game) tokenSeat(token string) int {
+	for i, t := range g.SeatTokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// moveAllowed vérifie qu'un coup est joué avec le jeton de la place qui a le trait.
+func (g *Game) moveAllowed(r *http.Request) bool {
+	return !g.GameOver && g.tokenSeat(r.PostFormValue(seatTokenField)) == g.CurrentPlayer
+}
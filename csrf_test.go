@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func postForm(values url.Values) *http.Request {
+	r := httptest.NewRequest("POST", "/connect4", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestCheckCSRF(t *testing.T) {
+	p := &Player{ID: "p1"}
+	token := p.csrfToken()
+	if token == "" || p.csrfToken() != token {
+		t.Fatalf("jeton instable: %q puis %q", token, p.csrfToken())
+	}
+	other := (&Player{ID: "p2"}).csrfToken()
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		header string
+		ok     bool
+	}{
+		{"champ du formulaire", url.Values{"csrf": {token}}, "", true},
+		{"en-tête", nil, token, true},
+		{"absent", nil, "", false},
+		{"vide", url.Values{"csrf": {""}}, "", false},
+		{"jeton d'un autre visiteur", url.Values{"csrf": {other}}, "", false},
+		{"jeton tronqué", url.Values{"csrf": {token[:len(token)-1]}}, "", false},
+	}
+	for _, tt := range tests {
+		r := postForm(tt.form)
+		if tt.header != "" {
+			r.Header.Set(csrfHeader, tt.header)
+		}
+		if got := checkCSRF(r, p); got != tt.ok {
+			t.Errorf("%s: checkCSRF = %v, attendu %v", tt.name, got, tt.ok)
+		}
+	}
+}
+
+func TestSeatTokens(t *testing.T) {
+	g := newTestGame("normal")
+	t1, t2 := g.seatToken(1), g.seatToken(2)
+	if t1 == "" || t2 == "" || t1 == t2 {
+		t.Fatalf("jetons des places: %q, %q", t1, t2)
+	}
+	if g.seatToken(0) != "" || g.seatToken(3) != "" {
+		t.Error("jeton pour une place inexistante")
+	}
+
+	tests := []struct {
+		token string
+		seat  int
+	}{
+		{t1, 1},
+		{t2, 2},
+		{"", 0},
+		{"inconnu", 0},
+	}
+	for _, tt := range tests {
+		if got := g.tokenSeat(tt.token); got != tt.seat {
+			t.Errorf("tokenSeat(%q) = %d, attendu %d", tt.token, got, tt.seat)
+		}
+	}
+
+	// Seule la place qui a le trait peut jouer, et plus personne une fois la partie finie
+	move := func(token string) bool { return g.moveAllowed(postForm(url.Values{"seat": {token}, "col": {"0"}})) }
+	if !move(t1) || move(t2) || move("") {
+		t.Error("trait du joueur 1: mauvaise place acceptée ou bonne place refusée")
+	}
+	g.DropToken(0)
+	if move(t1) || !move(t2) {
+		t.Error("trait du joueur 2: mauvaise place acceptée ou bonne place refusée")
+	}
+	g.finish(1)
+	if move(t1) || move(t2) {
+		t.Error("coup accepté après la fin de la partie")
+	}
+}
+
+func TestAssignSeatTokensKeepsExisting(t *testing.T) {
+	g := &Game{SeatTokens: [2]string{"gardé", ""}}
+	g.assignSeatTokens()
+	if g.SeatTokens[0] != "gardé" || g.SeatTokens[1] == "" {
+		t.Fatalf("jetons après assignSeatTokens: %v", g.SeatTokens)
+	}
+}
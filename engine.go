@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocole des moteurs externes (une commande par ligne sur stdin, une réponse par ligne sur stdout):
+//
+//	→ p4                                   poignée de main
+//	← p4ok <nom>
+//	→ newgame <lignes> <colonnes> <mode> <joueur>
+//	→ position <gravité> <trait> <ligne0>/<ligne1>/...   cases 0 (vide), 1 ou 2, de haut en bas
+//	→ go <temps restant ms> <incrément ms>
+//	← bestmove <emplacement>              colonne, ou ligne en gravité latérale
+//	→ quit
+//
+// Les lignes inattendues du moteur (ex: "info ...") sont ignorées.
+
+const (
+	enginesPath        = "engines.json"
+	engineStartTimeout = 5 * time.Second
+	engineMoveTimeout  = 10 * time.Second
+)
+
+// EngineConfig décrit un moteur externe lancé comme processus.
+type EngineConfig struct {
+	Name          string   `json:"name"`
+	Label         string   `json:"label"`
+	Command       string   `json:"command"`
+	Args          []string `json:"args"`
+	MoveTimeoutMs int      `json:"move_timeout_ms"` // sans pendule (0 = valeur par défaut)
+}
+
+// Engine est un moteur externe; le processus est lancé à la première utilisation et relancé après un plantage.
+type Engine struct {
+	EngineConfig
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string
+	lastGame *Game
+	lastSeat int
+}
+
+// engines contient les moteurs configurés, indexés par nom.
+var engines = map[string]*Engine{}
+
+// loadEngines lit la liste des moteurs externes; un fichier absent n'est pas une erreur.
+func loadEngines(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []EngineConfig
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, cfg := range list {
+		if cfg.Name == "" || cfg.Command == "" {
+			return fmt.Errorf("%s: moteur sans nom ou sans commande", path)
+		}
+		if _, ok := aiProfiles[cfg.Name]; ok {
+			return fmt.Errorf("%s: le moteur %q porte le nom d'un profil d'IA", path, cfg.Name)
+		}
+		if cfg.Label == "" {
+			cfg.Label = cfg.Name
+		}
+		engines[cfg.Name] = &Engine{EngineConfig: cfg}
+	}
+	return nil
+}
+
+// sortedEngines retourne les moteurs par ordre alphabétique.
+func sortedEngines() []*Engine {
+	list := make([]*Engine, 0, len(engines))
+	for _, e := range engines {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// closeEngines arrête tous les processus de moteurs.
+func closeEngines() {
+	for _, e := range engines {
+		e.Close()
+	}
+}
+
+// start lance le processus et vérifie la poignée de main. Appelé avec e.mu verrouillé.
+func (e *Engine) start() error {
+	cmd := exec.Command(e.Command, e.Args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("moteur %s: %w", e.Name, err)
+	}
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(lines)
+		cmd.Wait()
+	}()
+	e.cmd, e.stdin, e.lines, e.lastGame = cmd, stdin, lines, nil
+	if err := e.send("p4"); err != nil {
+		e.kill()
+		return err
+	}
+	if _, err := e.expect("p4ok", engineStartTimeout); err != nil {
+		e.kill()
+		return err
+	}
+	return nil
+}
+
+// kill arrête le processus sans attendre de réponse. Appelé avec e.mu verrouillé.
+func (e *Engine) kill() {
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	if e.lines != nil {
+		// Vide la sortie restante pour libérer le lecteur
+		go func(lines chan string) {
+			for range lines {
+			}
+		}(e.lines)
+	}
+	e.cmd, e.stdin, e.lines, e.lastGame = nil, nil, nil, nil
+}
+
+// send écrit une commande au moteur.
+func (e *Engine) send(format string, args ...any) error {
+	_, err := fmt.Fprintf(e.stdin, format+"\n", args...)
+	if err != nil {
+		return fmt.Errorf("moteur %s: %w", e.Name, err)
+	}
+	return nil
+}
+
+// expect attend une ligne commençant par keyword et retourne la suite.
+func (e *Engine) expect(keyword string, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return "", fmt.Errorf("moteur %s: processus arrêté", e.Name)
+			}
+			if rest, found := strings.CutPrefix(line, keyword); found && (rest == "" || rest[0] == ' ') {
+				return strings.TrimSpace(rest), nil
+			}
+		case <-timer.C:
+			return "", fmt.Errorf("moteur %s: pas de réponse %q après %s", e.Name, keyword, timeout)
+		}
+	}
+}
+
+// positionString encode le plateau pour la commande position.
+func positionString(g *Game) string {
+	rows := make([]string, g.Rows)
+	for r, row := range g.Board {
+		var b strings.Builder
+		for _, cell := range row {
+			b.WriteByte(byte('0' + cell))
+		}
+		rows[r] = b.String()
+	}
+	return strings.Join(rows, "/")
+}
+
+// gravityName retourne le nom de la gravité dans le protocole.
+func gravityName(gr Gravity) string {
+	switch gr {
+	case GravityUp:
+		return "up"
+	case GravityLeft:
+		return "left"
+	case GravityRight:
+		return "right"
+	default:
+		return "down"
+	}
+}
+
+// Move demande un coup au moteur. Un dépassement de temps ou un plantage arrête le processus,
+// qui sera relancé au prochain appel.
+func (e *Engine) Move(g *Game) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cmd == nil {
+		if err := e.start(); err != nil {
+			return -1, err
+		}
+	}
+
+	timeout := engineMoveTimeout
+	if e.MoveTimeoutMs > 0 {
+		timeout = time.Duration(e.MoveTimeoutMs) * time.Millisecond
+	}
+	if g.TimeControl.Enabled() {
+		timeout = g.TimeLeft(g.CurrentPlayer)
+	}
+	err := func() error {
+		if e.lastGame != g || e.lastSeat != g.CurrentPlayer {
+			if err := e.send("newgame %d %d %s %d", g.Rows, g.Cols, g.Mode, g.CurrentPlayer); err != nil {
+				return err
+			}
+			e.lastGame, e.lastSeat = g, g.CurrentPlayer
+		}
+		if err := e.send("position %s %d %s", gravityName(g.Gravity), g.CurrentPlayer, positionString(g)); err != nil {
+			return err
+		}
+		return e.send("go %d %d", timeout.Milliseconds(), g.TimeControl.Increment.Milliseconds())
+	}()
+	if err != nil {
+		e.kill()
+		return -1, err
+	}
+	reply, err := e.expect("bestmove", timeout)
+	if err != nil {
+		e.kill()
+		return -1, err
+	}
+	slot, err := strconv.Atoi(reply)
+	if err != nil {
+		return -1, fmt.Errorf("moteur %s: coup invalide %q", e.Name, reply)
+	}
+	if _, _, ok := g.landingCell(slot); !ok {
+		return -1, fmt.Errorf("moteur %s: coup illégal %d", e.Name, slot)
+	}
+	return slot, nil
+}
+
+// Close demande au moteur de s'arrêter.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cmd == nil {
+		return
+	}
+	e.send("quit")
+	e.stdin.Close()
+	done := make(chan struct{})
+	go func(lines chan string) {
+		for range lines {
+		}
+		close(done)
+	}(e.lines)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+	e.lines = nil
+	e.kill()
+}
+
+// engineBot fait jouer un moteur externe dans l'arène.
+type engineBot struct {
+	engine *Engine
+}
+
+func (b engineBot) Name() string  { return b.engine.Name }
+func (b engineBot) Label() string { return b.engine.Label }
+
+func (b engineBot) Move(g *Game) (int, error) { return b.engine.Move(g) }
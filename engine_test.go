@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestPositionString(t *testing.T) {
+	g := NewGame(4, 5, 0, "", "", "", "gauche", "", ModeHumanVsHuman, AIEasy)
+	g.Board[3][0], g.Board[2][4] = 1, 2
+	if got, want := positionString(g), "00000/00000/00002/10000"; got != want {
+		t.Errorf("positionString = %q, attendu %q", got, want)
+	}
+	names := map[Gravity]string{GravityDown: "down", GravityUp: "up", GravityLeft: "left", GravityRight: "right"}
+	for gr, want := range names {
+		if got := gravityName(gr); got != want {
+			t.Errorf("gravityName(%v) = %q, attendu %q", gr, got, want)
+		}
+	}
+}
+
+func TestLoadEngines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, data string
+		ok         bool
+	}{
+		{"sans commande", `[{"name": "muet"}]`, false},
+		{"nom d'un profil", `[{"name": "easy", "command": "true"}]`, false},
+		{"valide", `[{"name": "essai-moteur", "command": "true"}]`, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "engines.json")
+		os.WriteFile(path, []byte(tt.data), 0o644)
+		if err := loadEngines(path); (err == nil) != tt.ok {
+			t.Errorf("%s: erreur %v", tt.name, err)
+		}
+	}
+	defer delete(engines, "essai-moteur")
+	if e := engines["essai-moteur"]; e == nil || e.Label != "essai-moteur" {
+		t.Errorf("moteur chargé: %+v", e)
+	}
+}
+
+// exampleEngine lance le moteur Python d'exemple, ou saute le test sans interpréteur.
+func exampleEngine(t *testing.T) *Engine {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 absent")
+	}
+	e := &Engine{EngineConfig: EngineConfig{Name: "random-py", Command: "python3",
+		Args: []string{"examples/engines/random_engine.py"}, MoveTimeoutMs: 2000}}
+	t.Cleanup(e.Close)
+	return e
+}
+
+func TestEngineFullGames(t *testing.T) {
+	e := exampleEngine(t)
+	for _, mode := range gravityModes {
+		g := NewGame(6, 7, 6, "", "A", "B", mode, "", ModeHumanVsHuman, AIEasy)
+		for !g.GameOver {
+			slot, err := e.Move(g)
+			if err != nil {
+				t.Fatalf("%s, coup %d: %v", mode, len(g.History)+1, err)
+			}
+			if !g.DropToken(slot) {
+				t.Fatalf("%s: coup %d refusé", mode, slot)
+			}
+		}
+	}
+}
+
+func TestEngineEntryRules(t *testing.T) {
+	e := exampleEngine(t)
+	// Seule la case (2, 6) est libre: elle n'est atteignable qu'en entrant par la droite
+	g := newTestGame("rotation")
+	for r := range g.Board {
+		for c := range g.Board[r] {
+			g.Board[r][c] = 1 + (r+c/2)%2
+		}
+	}
+	g.Board[2][6] = 0
+	g.Gravity = GravityLeft
+	slot, err := e.Move(g)
+	if err != nil || slot != 2 {
+		t.Fatalf("Move = %d, %v; attendu la ligne 2", slot, err)
+	}
+}
+
+func TestEngineRestartAfterCrash(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh absent")
+	}
+	// Le moteur répond à la poignée de main puis s'arrête
+	e := &Engine{EngineConfig: EngineConfig{Name: "fragile", Command: "sh", Args: []string{"-c", "read l; echo p4ok fragile"}, MoveTimeoutMs: 1000}}
+	defer e.Close()
+	g := newTestGame("normal")
+	for range 2 {
+		if _, err := e.Move(g); err == nil {
+			t.Fatal("coup accepté d'un moteur arrêté")
+		}
+		e.mu.Lock()
+		running := e.cmd != nil
+		e.mu.Unlock()
+		if running {
+			t.Fatal("processus conservé après un plantage")
+		}
+	}
+}
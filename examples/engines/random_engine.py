@@ -0,0 +1,50 @@
+#!/usr/bin/env python3
+"""Moteur d'exemple pour le protocole texte de Power4: joue un coup valide au hasard,
+sans aucune réflexion."""
+import random
+import sys
+
+rows = cols = 0
+gravity = "down"
+board = []
+
+
+def playable(slot):
+    """Retourne True si l'emplacement (colonne ou ligne) a encore une case libre.
+
+    Mêmes règles que le serveur: le jeton tombe sur la case libre la plus proche
+    du bord de la gravité, quelle que soit sa position dans l'emplacement.
+    """
+    if gravity in ("left", "right"):
+        return 0 in board[slot]
+    return any(row[slot] == 0 for row in board)
+
+
+def slots():
+    n = rows if gravity in ("left", "right") else cols
+    return [s for s in range(n) if playable(s)]
+
+
+def send(line):
+    sys.stdout.write(line + "\n")
+    sys.stdout.flush()
+
+
+for raw in sys.stdin:
+    parts = raw.split()
+    if not parts:
+        continue
+    cmd = parts[0]
+    if cmd == "p4":
+        send("p4ok random-py")
+    elif cmd == "newgame":
+        rows, cols = int(parts[1]), int(parts[2])
+    elif cmd == "position":
+        gravity = parts[1]
+        board = [[int(c) for c in row] for row in parts[3].split("/")]
+    elif cmd == "go":
+        moves = slots()
+        send("info %d coups possibles" % len(moves))
+        send("bestmove %d" % (random.choice(moves) if moves else -1))
+    elif cmd == "quit":
+        break
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTestGame(mode string) *Game {
+	return NewGame(6, 7, 0, "easy", "Alice", "Bob", mode, "classic", ModeHumanVsHuman, AIEasy)
+}
+
+func TestLandingCell(t *testing.T) {
+	tests := []struct {
+		mode     string
+		block    [2]int // case occupée avant le coup ({-1, -1}: aucune)
+		slot     int
+		row, col int
+	}{
+		{"normal", [2]int{-1, -1}, 3, 5, 3},
+		{"inverse", [2]int{-1, -1}, 3, 0, 3},
+		{"gauche", [2]int{-1, -1}, 2, 2, 0},
+		{"droite", [2]int{-1, -1}, 2, 2, 6},
+		// Le jeton se pose sur le jeton déjà au bord de la gravité
+		{"normal", [2]int{5, 3}, 3, 4, 3},
+		{"inverse", [2]int{0, 3}, 3, 1, 3},
+		{"gauche", [2]int{2, 0}, 2, 2, 1},
+		{"droite", [2]int{2, 6}, 2, 2, 5},
+		// Un jeton côté entrée n'empêche pas de tomber sur la case libre la plus proche du bord
+		{"normal", [2]int{0, 3}, 3, 5, 3},
+		{"inverse", [2]int{5, 3}, 3, 0, 3},
+		{"gauche", [2]int{2, 6}, 2, 2, 0},
+		{"droite", [2]int{2, 0}, 2, 2, 6},
+	}
+	for _, tt := range tests {
+		g := newTestGame(tt.mode)
+		if tt.block[0] >= 0 {
+			g.Board[tt.block[0]][tt.block[1]] = 2
+		}
+		row, col, ok := g.landingCell(tt.slot)
+		if !ok || row != tt.row || col != tt.col {
+			t.Errorf("%s: landingCell(%d) = (%d, %d, %v), attendu (%d, %d)", tt.mode, tt.slot, row, col, ok, tt.row, tt.col)
+		}
+		if !g.DropToken(tt.slot) || g.Board[tt.row][tt.col] != 1 {
+			t.Errorf("%s: le jeton n'est pas en (%d, %d)", tt.mode, tt.row, tt.col)
+		}
+	}
+}
+
+func TestInverseFlip(t *testing.T) {
+	// Cinq jetons sur la ligne du haut, puis la gravité repasse vers le bas
+	g := newTestGame("inverse")
+	for slot := range 5 {
+		g.DropToken(slot)
+	}
+	if g.Gravity != GravityDown {
+		t.Fatalf("gravité après 5 coups = %v, attendu %v", g.Gravity, GravityDown)
+	}
+	if moves := g.getValidMoves(); !slices.Equal(moves, []int{0, 1, 2, 3, 4, 5, 6}) {
+		t.Errorf("coups jouables %v, attendu toutes les colonnes", moves)
+	}
+	if g.isDraw() || g.GameOver {
+		t.Fatal("partie nulle avec un plateau à moitié vide")
+	}
+	if !g.DropToken(0) || g.Board[5][0] != 2 {
+		t.Error("le jeton ne tombe pas au bas de la colonne 0")
+	}
+}
+
+func TestLandingCellOutOfRange(t *testing.T) {
+	for _, mode := range []string{"normal", "gauche"} {
+		g := newTestGame(mode)
+		for _, slot := range []int{-1, g.slotCount()} {
+			if _, _, ok := g.landingCell(slot); ok {
+				t.Errorf("%s: emplacement %d accepté", mode, slot)
+			}
+		}
+	}
+}
+
+func TestRotationCycle(t *testing.T) {
+	g := GravityDown
+	var seen []Gravity
+	for range rotationOrder {
+		seen = append(seen, g)
+		g = g.next()
+	}
+	if !slices.Equal(seen, rotationOrder) || g != GravityDown {
+		t.Fatalf("cycle de rotation = %v, attendu %v", seen, rotationOrder)
+	}
+
+	// La gravité tourne après le cinquième coup
+	game := newTestGame("rotation")
+	for i, slot := range []int{0, 1, 2, 3, 5} {
+		if game.Gravity != GravityDown {
+			t.Fatalf("gravité %v avant le coup %d", game.Gravity, i+1)
+		}
+		game.DropToken(slot)
+	}
+	if game.Gravity != GravityLeft {
+		t.Fatalf("gravité après 5 coups = %v, attendu %v", game.Gravity, GravityLeft)
+	}
+}
+
+func TestIsDraw(t *testing.T) {
+	full := func(g *Game) {
+		for r := range g.Board {
+			for c := range g.Board[r] {
+				g.Board[r][c] = 1 + (r+c/2)%2
+			}
+		}
+	}
+	tests := []struct {
+		name  string
+		setup func(g *Game)
+		moves map[Gravity][]int // emplacements jouables selon la gravité (absent: aucun, partie nulle)
+	}{
+		{"plateau vide", func(g *Game) {}, map[Gravity][]int{
+			GravityDown:  {0, 1, 2, 3, 4, 5, 6},
+			GravityUp:    {0, 1, 2, 3, 4, 5, 6},
+			GravityLeft:  {0, 1, 2, 3, 4, 5},
+			GravityRight: {0, 1, 2, 3, 4, 5},
+		}},
+		{"plateau plein", full, nil},
+		// Une case libre reste atteignable quelle que soit la gravité
+		{"une case libre sur le bord du bas", func(g *Game) { full(g); g.Board[5][3] = 0 }, map[Gravity][]int{
+			GravityDown: {3}, GravityUp: {3}, GravityLeft: {5}, GravityRight: {5},
+		}},
+		{"une case libre au centre", func(g *Game) { full(g); g.Board[2][3] = 0 }, map[Gravity][]int{
+			GravityDown: {3}, GravityUp: {3}, GravityLeft: {2}, GravityRight: {2},
+		}},
+	}
+	for _, tt := range tests {
+		for _, gravity := range rotationOrder {
+			g := newTestGame("rotation")
+			g.Gravity = gravity
+			tt.setup(g)
+			want := tt.moves[gravity]
+			if got := g.getValidMoves(); !slices.Equal(got, want) {
+				t.Errorf("%s (gravité %v): coups jouables %v, attendu %v", tt.name, gravity, got, want)
+			}
+			if got := g.isDraw(); got != (len(want) == 0) {
+				t.Errorf("%s (gravité %v): isDraw = %v", tt.name, gravity, got)
+			}
+		}
+	}
+}
+
+func TestVerticalWin(t *testing.T) {
+	g := newTestGame("normal")
+	for _, slot := range []int{0, 1, 0, 1, 0, 1, 0} {
+		g.DropToken(slot)
+	}
+	if !g.GameOver || g.Winner != 1 {
+		t.Fatalf("victoire verticale non détectée: GameOver=%v Winner=%d", g.GameOver, g.Winner)
+	}
+	if g.DropToken(2) {
+		t.Fatal("coup accepté après la fin de la partie")
+	}
+}
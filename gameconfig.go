@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxNameLength est la longueur maximale d'un pseudo, en caractères.
+const maxNameLength = 16
+
+// skins sont les thèmes du plateau proposés sur la page d'accueil (classes skin-* de style.css).
+var skins = []string{"classic", "neon", "retro"}
+
+// gravityModes sont les modes de gravité de la page de choix du mode.
+var gravityModes = []string{"normal", "inverse", "gauche", "droite", "rotation"}
+
+// seriesLengths sont les longueurs de série proposées (1: partie simple).
+var seriesLengths = []int{1, 3, 5, 7}
+
+// GameConfig est la configuration d'une partie locale: formulaire d'accueil, page de choix du mode,
+// adresse de /connect4 et API. Elle est lue et validée une fois par parseGameConfig.
+type GameConfig struct {
+	Username   string
+	Username2  string
+	Difficulty string // clé de boardPresets
+	Mode       string // mode de gravité; vide tant qu'il n'est pas choisi
+	Skin       string
+	GameMode   GameMode
+	AILevel    string // niveau, profil d'IA ou bot adverse (contre l'IA uniquement)
+	Cadence    string // vide: sans pendule
+	Serie      int    // nombre de parties de la série
+}
+
+// parseGameConfig lit et valide la configuration; les valeurs absentes prennent leur valeur par défaut.
+// L'erreur liste tous les paramètres invalides, un par ligne.
+func parseGameConfig(v url.Values) (GameConfig, error) {
+	var errs []error
+	cfg := GameConfig{
+		Username:   strings.TrimSpace(v.Get("username")),
+		Username2:  strings.TrimSpace(v.Get("username2")),
+		Difficulty: v.Get("difficulty"),
+		Mode:       v.Get("mode"),
+		Skin:       v.Get("skin"),
+		AILevel:    v.Get("ailevel"),
+		Cadence:    strings.TrimSpace(v.Get("cadence")),
+		Serie:      1,
+	}
+	for _, name := range []struct{ label, value string }{{"pseudo du joueur 1", cfg.Username}, {"pseudo du joueur 2", cfg.Username2}} {
+		if err := validateName(name.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", name.label, err))
+		}
+	}
+	if cfg.Difficulty == "" {
+		cfg.Difficulty = "easy"
+	} else if _, ok := boardPresets[cfg.Difficulty]; !ok {
+		errs = append(errs, fmt.Errorf("difficulté inconnue: %q", cfg.Difficulty))
+	}
+	if cfg.Mode != "" && !slices.Contains(gravityModes, cfg.Mode) {
+		errs = append(errs, fmt.Errorf("mode de gravité inconnu: %q", cfg.Mode))
+	}
+	if cfg.Skin == "" {
+		cfg.Skin = "classic"
+	} else if !slices.Contains(skins, cfg.Skin) {
+		errs = append(errs, fmt.Errorf("skin inconnu: %q", cfg.Skin))
+	}
+	switch v.Get("gamemode") {
+	case "", "human":
+		cfg.GameMode, cfg.AILevel = ModeHumanVsHuman, ""
+	case "ai":
+		cfg.GameMode = ModeHumanVsAI
+		if cfg.AILevel == "" {
+			cfg.AILevel = "easy"
+		}
+		if !knownOpponent(cfg.AILevel) {
+			errs = append(errs, fmt.Errorf("adversaire inconnu: %q", cfg.AILevel))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("type de partie inconnu: %q", v.Get("gamemode")))
+	}
+	if cfg.Cadence != "" && parseTimeControl(cfg.Cadence).Name == "" {
+		errs = append(errs, fmt.Errorf("cadence invalide: %q", cfg.Cadence))
+	}
+	if s := v.Get("serie"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || !slices.Contains(seriesLengths, n) {
+			errs = append(errs, fmt.Errorf("longueur de série invalide: %q", s))
+		} else {
+			cfg.Serie = n
+		}
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// validateName vérifie un pseudo: longueur maximale et caractères imprimables.
+func validateName(name string) error {
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Errorf("dépasse %d caractères", maxNameLength)
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return errors.New("contient des caractères non imprimables")
+		}
+	}
+	return nil
+}
+
+// knownOpponent indique si l'adversaire demandé existe: niveau, profil d'IA, moteur ou bot HTTP.
+func knownOpponent(name string) bool {
+	switch name {
+	case "easy", "medium", "hard":
+		return true
+	}
+	return aiProfiles[name] != nil || externalBot(name) != nil
+}
+
+// values encode la configuration pour l'adresse de la page suivante.
+func (c GameConfig) values() url.Values {
+	v := url.Values{}
+	v.Set("username", c.Username)
+	if c.Username2 != "" {
+		v.Set("username2", c.Username2)
+	}
+	v.Set("difficulty", c.Difficulty)
+	if c.Mode != "" {
+		v.Set("mode", c.Mode)
+	}
+	v.Set("skin", c.Skin)
+	if c.GameMode == ModeHumanVsAI {
+		v.Set("gamemode", "ai")
+		v.Set("ailevel", c.AILevel)
+	} else {
+		v.Set("gamemode", "human")
+	}
+	if c.Cadence != "" {
+		v.Set("cadence", c.Cadence)
+	}
+	if c.Serie > 1 {
+		v.Set("serie", strconv.Itoa(c.Serie))
+	}
+	return v
+}
+
+// ErrorPage est une page d'erreur: un titre, le détail des problèmes et un lien de retour.
+type ErrorPage struct {
+	Title   string
+	Details []string
+	BackURL string
+}
+
+// renderError affiche une page d'erreur. Le détail d'une erreur jointe (errors.Join) est affiché ligne par ligne.
+func renderError(w http.ResponseWriter, status int, title string, err error, backURL string) {
+	page := ErrorPage{Title: title, BackURL: backURL}
+	if err != nil {
+		page.Details = strings.Split(err.Error(), "\n")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	errorTmpl.Execute(w, page)
+}
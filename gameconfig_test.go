@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseGameConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		want   GameConfig
+		errors []string // fragments attendus dans l'erreur, un par paramètre invalide
+	}{
+		{
+			name:  "valeurs par défaut",
+			query: "username=Alice",
+			want:  GameConfig{Username: "Alice", Difficulty: "easy", Skin: "classic", GameMode: ModeHumanVsHuman, Serie: 1},
+		},
+		{
+			name:  "contre l'IA",
+			query: "username=Alice&gamemode=ai&difficulty=hard&mode=rotation&skin=neon&cadence=3%2B2&serie=5",
+			want: GameConfig{Username: "Alice", Difficulty: "hard", Mode: "rotation", Skin: "neon",
+				GameMode: ModeHumanVsAI, AILevel: "easy", Cadence: "3+2", Serie: 5},
+		},
+		{
+			name:  "à deux, niveau ignoré",
+			query: "username=%20Alice%20&username2=Bob&gamemode=human&ailevel=hard",
+			want:  GameConfig{Username: "Alice", Username2: "Bob", Difficulty: "easy", Skin: "classic", GameMode: ModeHumanVsHuman, Serie: 1},
+		},
+		{
+			name:   "pseudo trop long",
+			query:  "username=" + strings.Repeat("a", maxNameLength+1),
+			errors: []string{"pseudo du joueur 1"},
+		},
+		{
+			name:   "caractère de contrôle",
+			query:  "username=Bob%0AX-Injected",
+			errors: []string{"non imprimables"},
+		},
+		{
+			name:   "série paire",
+			query:  "username=Alice&serie=2",
+			errors: []string{"longueur de série"},
+		},
+		{
+			name:  "plusieurs erreurs",
+			query: "username=Alice&difficulty=x&mode=y&skin=z&gamemode=ai&ailevel=inconnu&cadence=vite&serie=deux",
+			errors: []string{"difficulté inconnue", "mode de gravité inconnu", "skin inconnu", "adversaire inconnu",
+				"cadence invalide", "longueur de série invalide"},
+		},
+		{
+			name:   "type de partie inconnu",
+			query:  "username=Alice&gamemode=robot",
+			errors: []string{"type de partie inconnu"},
+		},
+	}
+	for _, tt := range tests {
+		v, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := parseGameConfig(v)
+		if len(tt.errors) == 0 {
+			if err != nil {
+				t.Errorf("%s: erreur inattendue: %v", tt.name, err)
+			} else if cfg != tt.want {
+				t.Errorf("%s: %+v, attendu %+v", tt.name, cfg, tt.want)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: aucune erreur", tt.name)
+			continue
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != len(tt.errors) {
+			t.Errorf("%s: %d erreurs, attendu %d: %v", tt.name, len(lines), len(tt.errors), err)
+		}
+		for _, frag := range tt.errors {
+			if !strings.Contains(err.Error(), frag) {
+				t.Errorf("%s: %q absent de l'erreur %q", tt.name, frag, err)
+			}
+		}
+	}
+}
+
+func TestGameConfigValuesRoundTrip(t *testing.T) {
+	v, _ := url.ParseQuery("username=A%26B%23C&username2=D&gamemode=ai&ailevel=medium&difficulty=normal&mode=gauche&skin=retro&cadence=3%2B2&serie=3")
+	cfg, err := parseGameConfig(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := parseGameConfig(cfg.values())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != cfg {
+		t.Fatalf("après values(): %+v, attendu %+v", again, cfg)
+	}
+}
+
+func TestPlayerRenameValidates(t *testing.T) {
+	p := &Player{Name: "Alice"}
+	if err := p.rename("Bob\nX-Injected: 1"); err == nil {
+		t.Error("pseudo avec saut de ligne accepté")
+	}
+	if err := p.rename(strings.Repeat("é", maxNameLength+1)); err == nil {
+		t.Error("pseudo trop long accepté")
+	}
+	if p.Name != "Alice" {
+		t.Errorf("pseudo modifié malgré l'erreur: %q", p.Name)
+	}
+	if err := p.rename("  Bob  "); err != nil || p.Name != "Bob" {
+		t.Errorf("rename(\"  Bob  \") = %v, pseudo %q", err, p.Name)
+	}
+}
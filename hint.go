@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	hintTimeLimit = 1500 * time.Millisecond
+	hintMaxDepth  = 8
+)
+
+// MoveScore est l'évaluation d'un coup du point de vue du joueur qui a le trait.
+type MoveScore struct {
+	Slot    int    `json:"slot"`
+	Score   int    `json:"score"`
+	Verdict string `json:"verdict"` // "gagnant", "perdant" ou "incertain"
+}
+
+// verdictFor classe un score de recherche.
+func verdictFor(score int) string {
+	switch {
+	case score > winScore/2:
+		return "gagnant"
+	case score < -winScore/2:
+		return "perdant"
+	default:
+		return "incertain"
+	}
+}
+
+// scoreMoves évalue chaque coup jouable à la profondeur donnée, pour le joueur qui a le trait.
+// Retourne nil si la limite de temps de la partie est atteinte en cours de recherche.
+func (g *Game) scoreMoves(depth int) []MoveScore {
+	player := g.CurrentPlayer
+	var scores []MoveScore
+	for _, slot := range g.getValidMoves() {
+		row, cell := g.simulateMove(slot, player)
+		if row == -1 {
+			continue
+		}
+		var eval int
+		if g.checkWin(row, cell) {
+			eval = winScore + depth
+			if player == 1 {
+				eval = -eval
+			}
+		} else {
+			// Le joueur 2 maximise: après un coup du joueur 1, c'est à lui
+			eval, _ = g.minimax(depth-1, player == 1, -infScore, infScore)
+		}
+		g.Board[row][cell] = 0
+		if g.searchAborted {
+			return nil
+		}
+		if player == 1 {
+			eval = -eval
+		}
+		scores = append(scores, MoveScore{Slot: slot, Score: eval, Verdict: verdictFor(eval)})
+	}
+	return scores
+}
+
+// analyseMoves approfondit scoreMoves jusqu'à maxDepth ou jusqu'à la limite de temps (0 = sans limite).
+// Elle travaille sur un clone: l'appelant n'a pas besoin de garder le verrou de la partie.
+func analyseMoves(g *Game, maxDepth int, limit time.Duration) (scores []MoveScore, depth int) {
+	c := g.clone()
+	start := time.Now()
+	defer func() {
+		searchNodes.Add(c.searchNodes)
+		searchNanos.Add(int64(time.Since(start)))
+	}()
+	if limit > 0 {
+		c.searchDeadline = time.Now().Add(limit)
+	}
+	for d := 1; d <= maxDepth; d++ {
+		s := c.scoreMoves(d)
+		if s == nil {
+			break
+		}
+		scores, depth = s, d
+	}
+	return scores, depth
+}
+
+// searchNodes compte les positions visitées par toutes les recherches depuis le démarrage.
+var searchNodes atomic.Int64
+
+// bestSlot retourne le coup au meilleur score (-1 si aucun).
+func bestSlot(scores []MoveScore) int {
+	best := -1
+	bestScore := -infScore
+	for _, s := range scores {
+		if s.Score > bestScore {
+			best, bestScore = s.Slot, s.Score
+		}
+	}
+	return best
+}
+
+// hintHandler renvoie en JSON le meilleur coup et l'évaluation de chaque coup pour le joueur qui a le trait.
+// La recherche se fait sur une copie de la partie, hors du verrou de la session.
+// Les indices ne sont pas disponibles dans les salles en ligne.
+func hintHandler(w http.ResponseWriter, r *http.Request) {
+	sess := requestSession(w, r)
+	if sess == nil || sess.Room != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	sess.mu.Lock()
+	game := sess.Game
+	if game == nil || game.GameOver || (game.GameMode == ModeHumanVsAI && game.CurrentPlayer != 1) {
+		sess.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	snapshot := game.clone()
+	sess.mu.Unlock()
+
+	release, ok := acquireSearch(w, r)
+	if !ok {
+		return
+	}
+	scores, depth := analyseMoves(snapshot, hintMaxDepth, hintTimeLimit)
+	release()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Best       int         `json:"best"`
+		Depth      int         `json:"depth"`
+		Horizontal bool        `json:"horizontal"`
+		Moves      []MoveScore `json:"moves"`
+	}{
+		Best:       bestSlot(scores),
+		Depth:      depth,
+		Horizontal: snapshot.Gravity.Horizontal(),
+		Moves:      scores,
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+// threatGame retourne une partie où le joueur 1 menace d'aligner quatre jetons en colonne 4 (emplacement 3).
+func threatGame(toMove int) *Game {
+	g := newTestGame("normal")
+	for c := range 3 {
+		g.Board[5][c] = 1
+		g.Board[4][c] = 2
+	}
+	g.CurrentPlayer = toMove
+	return g
+}
+
+func TestAnalyseMovesWinningMove(t *testing.T) {
+	scores, depth := analyseMoves(threatGame(1), 3, 0)
+	if depth != 3 || len(scores) != 7 {
+		t.Fatalf("profondeur %d, %d coups évalués", depth, len(scores))
+	}
+	if best := bestSlot(scores); best != 3 {
+		t.Fatalf("meilleur coup %d, attendu 3", best)
+	}
+	for _, s := range scores {
+		if s.Slot == 3 && s.Verdict != "gagnant" {
+			t.Errorf("coup gagnant classé %q", s.Verdict)
+		}
+	}
+}
+
+func TestAnalyseMovesForcedBlock(t *testing.T) {
+	scores, _ := analyseMoves(threatGame(2), 2, 0)
+	if best := bestSlot(scores); best != 3 {
+		t.Fatalf("meilleur coup %d, attendu le blocage en 3", best)
+	}
+	for _, s := range scores {
+		if s.Slot != 3 && s.Verdict != "perdant" {
+			t.Errorf("coup %d classé %q alors qu'il laisse gagner l'adversaire", s.Slot, s.Verdict)
+		}
+	}
+}
+
+func TestAnalyseMovesLeavesGameUntouched(t *testing.T) {
+	g := threatGame(1)
+	before := g.positionHash()
+	analyseMoves(g, 4, 0)
+	if g.positionHash() != before || g.searchNodes != 0 {
+		t.Fatal("l'analyse a modifié la partie")
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	ratingK            = 32               // facteur K du classement Elo des parties en ligne
+	quickMatchWindow   = 100              // écart de classement accepté au départ par l'appariement rapide
+	quickMatchWiden    = 50               // élargissement de l'écart...
+	quickMatchWidenAge = 10 * time.Second // ...par tranche d'attente de l'hôte
+)
+
+// RoomSettings sont les paramètres de partie choisis à la création d'une salle.
+type RoomSettings struct {
+	Difficulty string
+	Mode       string
+	Cadence    string
+	BestOf     int
+}
+
+// Room est une salle de jeu en ligne entre deux visiteurs. L'hôte occupe la place 1.
+type Room struct {
+	RoomSettings
+	Public  bool // listée dans le salon (une salle privée n'est accessible que par son lien)
+	Quick   bool // créée par l'appariement rapide
+	Seats   [2]*Player
+	Created time.Time
+	Rated   bool // résultat de la partie en cours déjà pris en compte dans le classement
+}
+
+// parseRoomSettings lit et normalise les paramètres d'un formulaire de création de salle.
+func parseRoomSettings(r *http.Request) RoomSettings {
+	difficulty := r.FormValue("difficulty")
+	switch difficulty {
+	case "easy", "normal", "hard":
+	default:
+		difficulty = "easy"
+	}
+	// Séries de longueur impaire uniquement (comme /connect4): NewMatch arrondirait une longueur paire
+	bestOf, _ := strconv.Atoi(r.FormValue("serie"))
+	if !slices.Contains(seriesLengths, bestOf) {
+		bestOf = 1
+	}
+	return RoomSettings{
+		Difficulty: difficulty,
+		Mode:       normalizeMode(r.FormValue("mode")),
+		Cadence:    parseTimeControl(r.FormValue("cadence")).Name,
+		BestOf:     bestOf,
+	}
+}
+
+// waiting indique si la salle attend encore un adversaire.
+func (room *Room) waiting() bool {
+	return room.Seats[1] == nil
+}
+
+// seatOf retourne la place du joueur dans la salle (1 ou 2), 0 s'il n'y est pas assis.
+func (room *Room) seatOf(p *Player) int {
+	for i, seated := range room.Seats {
+		if seated == p {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// newRoomGame lance une partie de la salle, dans la série en cours si elle n'est pas terminée.
+// Tant que l'adversaire n'est pas arrivé, la partie ne sert qu'à afficher le plateau vide.
+func newRoomGame(room *Room, match *Match) *Game {
+	rows, cols, prefill := boardSize(room.Difficulty)
+	mutex.Lock()
+	name1, name2 := room.Seats[0].Name, ""
+	if room.Seats[1] != nil {
+		name2 = room.Seats[1].Name
+	}
+	mutex.Unlock()
+	g := NewGame(rows, cols, prefill, room.Difficulty, name1, name2, room.Mode, "classic", ModeHumanVsHuman, AIEasy)
+	if room.BestOf > 1 {
+		if match == nil || match.Over() {
+			match = NewMatch(room.BestOf)
+		}
+		g.Match = match
+		g.CurrentPlayer = match.Starter()
+	}
+	if !room.waiting() {
+		g.SetTimeControl(parseTimeControl(room.Cadence))
+	}
+	return g
+}
+
+// settle met à jour le classement des deux joueurs à la fin d'une partie. Appelé avec s.mu verrouillé.
+func (s *GameSession) settle() {
+	room, g := s.Room, s.Game
+	if room == nil || g == nil || !g.GameOver || room.Rated || room.waiting() {
+		return
+	}
+	room.Rated = true
+	score := 0.5
+	switch g.Winner {
+	case 1:
+		score = 1
+	case 2:
+		score = 0
+	}
+	mutex.Lock()
+	a, b := room.Seats[0], room.Seats[1]
+	expected := 1 / (1 + math.Pow(10, float64(b.Rating-a.Rating)/400))
+	delta := int(math.Round(ratingK * (score - expected)))
+	a.Rating += delta
+	b.Rating -= delta
+	mutex.Unlock()
+	s.persist()
+}
+
+// join assoit le joueur à la place libre et lance la partie. Appelé avec s.mu verrouillé.
+func (s *GameSession) join(ctx context.Context, p *Player) bool {
+	mutex.Lock()
+	room := s.Room
+	if !room.waiting() || room.seatOf(p) != 0 {
+		mutex.Unlock()
+		return false
+	}
+	room.Seats[1] = p
+	mutex.Unlock()
+	s.Game = newRoomGame(room, nil)
+	s.touch(ctx)
+	return true
+}
+
+// createRoom ouvre une salle dont le joueur est l'hôte.
+func createRoom(host *Player, settings RoomSettings, public, quick bool) *GameSession {
+	room := &Room{RoomSettings: settings, Public: public, Quick: quick, Created: time.Now()}
+	room.Seats[0] = host
+	s := newSession(room)
+	s.mu.Lock()
+	s.Game = newRoomGame(room, nil)
+	s.persist()
+	s.mu.Unlock()
+	return s
+}
+
+// findQuickMatch cherche une salle d'appariement rapide aux mêmes paramètres dont l'hôte a le classement
+// le plus proche; l'écart toléré s'élargit avec l'attente de l'hôte. Retourne aussi la salle que le joueur
+// attend déjà, le cas échéant.
+func findQuickMatch(p *Player, settings RoomSettings) (match, own *GameSession) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	bestGap := 0
+	for _, s := range sessions {
+		room := s.Room
+		if room == nil || !room.Quick || !room.waiting() || room.RoomSettings != settings {
+			continue
+		}
+		host := room.Seats[0]
+		if host == p {
+			own = s
+			continue
+		}
+		gap := host.Rating - p.Rating
+		if gap < 0 {
+			gap = -gap
+		}
+		window := quickMatchWindow + quickMatchWiden*int(time.Since(room.Created)/quickMatchWidenAge)
+		if gap > window {
+			continue
+		}
+		if match == nil || gap < bestGap {
+			match, bestGap = s, gap
+		}
+	}
+	return match, own
+}
+
+// LobbyEntry est une salle publique en attente d'adversaire, affichée dans le salon.
+type LobbyEntry struct {
+	ID      string
+	Host    string
+	Rating  int
+	Rows    int
+	Cols    int
+	Mode    string
+	Cadence string
+	BestOf  int
+	Quick   bool
+	Waiting string // durée d'attente lisible
+	Own     bool   // salle du visiteur
+}
+
+// openRooms retourne les salles publiques en attente d'adversaire, des plus anciennes aux plus récentes.
+func openRooms(viewer *Player) []LobbyEntry {
+	mutex.Lock()
+	defer mutex.Unlock()
+	type entry struct {
+		LobbyEntry
+		created time.Time
+	}
+	var list []entry
+	for id, s := range sessions {
+		room := s.Room
+		if room == nil || !room.Public || !room.waiting() {
+			continue
+		}
+		rows, cols, _ := boardSize(room.Difficulty)
+		list = append(list, entry{LobbyEntry{
+			ID:      id,
+			Host:    room.Seats[0].Name,
+			Rating:  room.Seats[0].Rating,
+			Rows:    rows,
+			Cols:    cols,
+			Mode:    room.Mode,
+			Cadence: room.Cadence,
+			BestOf:  room.BestOf,
+			Quick:   room.Quick,
+			Waiting: time.Since(room.Created).Round(time.Second).String(),
+			Own:     room.Seats[0] == viewer,
+		}, room.Created})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].created.Before(list[j].created) })
+	entries := make([]LobbyEntry, len(list))
+	for i, e := range list {
+		entries[i] = e.LobbyEntry
+	}
+	return entries
+}
+
+// lobbyHandler affiche le salon et traite la création de salles et l'appariement rapide.
+func lobbyHandler(w http.ResponseWriter, r *http.Request) {
+	player := currentPlayer(w, r)
+	if r.Method == "POST" {
+		if !checkCSRF(r, player) {
+			rejectCSRF(w, r)
+			return
+		}
+		if err := player.rename(r.FormValue("username")); err != nil {
+			renderError(w, http.StatusBadRequest, "Pseudo invalide", err, appPath("/lobby"))
+			return
+		}
+		player.setEmail(r.FormValue("email"))
+		settings := parseRoomSettings(r)
+		var sess *GameSession
+		switch r.FormValue("action") {
+		case "quick":
+			match, own := findQuickMatch(player, settings)
+			switch {
+			case match != nil:
+				match.mu.Lock()
+				joined := match.join(r.Context(), player)
+				match.mu.Unlock()
+				if joined {
+					sess = match
+					break
+				}
+				// Salle prise entre-temps: on attend dans la sienne
+				fallthrough
+			case own != nil:
+				sess = own
+			}
+			if sess == nil {
+				sess = createRoom(player, settings, true, true)
+			}
+		default:
+			sess = createRoom(player, settings, r.FormValue("visibility") != "private", false)
+		}
+		http.Redirect(w, r, appPath("/room?id="+sess.ID), http.StatusSeeOther)
+		return
+	}
+
+	pruneSessions()
+	mutex.Lock()
+	name, email, rating := player.Name, player.Email, player.Rating
+	mutex.Unlock()
+	lobbyTmpl.Execute(w, struct {
+		Username string
+		Email    string
+		Rating   int
+		CSRF     string
+		Rooms    []LobbyEntry
+		Live     []LiveEntry
+	}{
+		Username: name,
+		CSRF:     player.csrfToken(),
+		Email:    email,
+		Rating:   rating,
+		Rooms:    openRooms(player),
+		Live:     liveRooms(),
+	})
+}
+
+// roomHandler affiche une salle et traite les actions de ses joueurs: rejoindre, jouer, revanche, abandon.
+func roomHandler(w http.ResponseWriter, r *http.Request) {
+	player := currentPlayer(w, r)
+	sess := lookupSession(r.URL.Query().Get("id"))
+	if sess == nil || sess.Room == nil {
+		http.Error(w, "Salle introuvable", http.StatusNotFound)
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	room := sess.Room
+	seat := room.seatOf(player)
+	game := sess.Game
+	if game.checkTimeout() {
+		sess.touch(r.Context())
+	}
+
+	// Une partie commencée se regarde en lecture seule
+	if seat == 0 && !room.waiting() && r.Method != "POST" {
+		http.Redirect(w, r, appPath("/watch?id="+sess.ID), http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !checkCSRF(r, player) {
+			rejectCSRF(w, r)
+			return
+		}
+		switch {
+		case r.FormValue("join") == "1":
+			if err := player.rename(r.FormValue("username")); err != nil {
+				renderError(w, http.StatusBadRequest, "Pseudo invalide", err, appPath("/room?id="+sess.ID))
+				return
+			}
+			player.setEmail(r.FormValue("email"))
+			if sess.join(r.Context(), player) {
+				seat = 2
+			}
+		case r.FormValue("reset") == "1":
+			if seat != 0 && !room.waiting() && !game.GameOver {
+				game.Forfeited = true
+				game.finish(3 - seat)
+				sess.touch(r.Context())
+			} else if seat == 1 && room.waiting() {
+				mutex.Lock()
+				delete(sessions, sess.ID)
+				mutex.Unlock()
+				if store != nil && sess.persistent() {
+					store.Delete(sess.ID)
+				}
+			}
+			sess.settle()
+			http.Redirect(w, r, appPath("/lobby"), http.StatusSeeOther)
+			return
+		case r.FormValue("rematch") == "1":
+			if seat != 0 && game.GameOver {
+				sess.Game = newRoomGame(room, game.Match)
+				sess.Game.Chat, sess.Game.ChatSeq = game.Chat, game.ChatSeq
+				room.Rated = false
+				sess.touch(r.Context())
+			}
+		default:
+			if colStr := slotValue(r, game); colStr != "" && seat == game.CurrentPlayer && !room.waiting() {
+				if !game.moveAllowed(r) {
+					logger(r.Context()).Warn("coup refusé: jeton de place invalide", "game", sess.ID, "seat", seat)
+				} else if col, err := strconv.Atoi(colStr); err == nil && game.DropToken(col) {
+					sess.touch(r.Context())
+				}
+			}
+		}
+	}
+	sess.settle()
+	game = sess.Game
+
+	waiting := room.waiting()
+	resetLabel := "Retour au salon"
+	if seat != 0 && !waiting && !game.GameOver {
+		resetLabel = "Abandonner"
+	} else if seat == 1 && waiting {
+		resetLabel = "Fermer la salle"
+	}
+	data := newGameView(game, seat)
+	data.AnalyseURL = appPath("/analyse?id=" + sess.ID)
+	data.BoardHTML = renderBoard(game, boardView{
+		CanPlay:    !waiting && !game.GameOver && seat == game.CurrentPlayer,
+		Rematch:    seat != 0,
+		AnalyseURL: data.AnalyseURL,
+		ResetLabel: resetLabel,
+		CSRF:       player.csrfToken(),
+		SeatToken:  game.seatToken(seat),
+	})
+	sess.describe(&data, player)
+	data.CanJoin = waiting && seat == 0
+	data.ShareURL = absoluteURL(r, "/room?id="+sess.ID)
+	data.WatchURL = absoluteURL(r, "/watch?id="+sess.ID)
+	pageTmpl.Execute(w, data)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// forgetSession retire la partie de l'index à la fin du test.
+func forgetSession(t *testing.T, s *GameSession) *GameSession {
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, s.ID)
+		mutex.Unlock()
+	})
+	return s
+}
+
+func TestFindQuickMatch(t *testing.T) {
+	settings := RoomSettings{Difficulty: "easy", Mode: "normal", BestOf: 1}
+	me := &Player{ID: newID(), Name: "Moi", Rating: 1500}
+	far := forgetSession(t, createRoom(&Player{ID: newID(), Name: "Loin", Rating: 1700}, settings, true, true))
+	near := forgetSession(t, createRoom(&Player{ID: newID(), Name: "Proche", Rating: 1560}, settings, true, true))
+	forgetSession(t, createRoom(&Player{ID: newID(), Name: "Autre mode", Rating: 1500}, RoomSettings{Difficulty: "easy", Mode: "inverse", BestOf: 1}, true, true))
+	forgetSession(t, createRoom(&Player{ID: newID(), Name: "Salle normale", Rating: 1500}, settings, true, false))
+
+	match, own := findQuickMatch(me, settings)
+	if match != near || own != nil {
+		t.Fatalf("adversaire %v, attendu l'hôte au classement le plus proche", match)
+	}
+
+	// L'écart toléré s'élargit avec l'attente de l'hôte
+	mutex.Lock()
+	near.Room.Seats[1] = &Player{ID: newID(), Name: "Arrivé"}
+	far.Room.Created = time.Now().Add(-3 * quickMatchWidenAge)
+	mutex.Unlock()
+	if match, _ := findQuickMatch(me, settings); match != far {
+		t.Fatalf("adversaire %v, attendu l'hôte qui attend depuis longtemps", match)
+	}
+
+	mine := forgetSession(t, createRoom(me, settings, true, true))
+	if _, own := findQuickMatch(me, settings); own != mine {
+		t.Errorf("salle du joueur %v, attendu %v", own, mine)
+	}
+}
+
+func TestJoinAndSettle(t *testing.T) {
+	alice := &Player{ID: newID(), Name: "Alice", Rating: 1500}
+	bob := &Player{ID: newID(), Name: "Bob", Rating: 1500}
+	sess := forgetSession(t, createRoom(alice, RoomSettings{Difficulty: "easy", Mode: "normal", Cadence: "3+2", BestOf: 1}, true, false))
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.join(t.Context(), alice) {
+		t.Fatal("l'hôte a rejoint sa propre salle")
+	}
+	if !sess.join(t.Context(), bob) {
+		t.Fatal("place libre refusée")
+	}
+	g := sess.Game
+	if g.Username1 != "Alice" || g.Username2 != "Bob" || !g.TimeControl.Enabled() {
+		t.Fatalf("partie lancée: %s contre %s, pendule %v", g.Username1, g.Username2, g.TimeControl.Enabled())
+	}
+	if sess.join(t.Context(), &Player{ID: newID()}) {
+		t.Fatal("troisième joueur assis")
+	}
+
+	g.finish(1)
+	sess.settle()
+	sess.settle()
+	if alice.Rating != 1500+ratingK/2 || bob.Rating != 1500-ratingK/2 {
+		t.Errorf("classement après la victoire d'Alice: %d et %d", alice.Rating, bob.Rating)
+	}
+}
+
+func TestParseRoomSettings(t *testing.T) {
+	tests := []struct {
+		form url.Values
+		want RoomSettings
+	}{
+		{url.Values{"difficulty": {"hard"}, "mode": {"rotation"}, "cadence": {"jours-2"}, "serie": {"3"}},
+			RoomSettings{Difficulty: "hard", Mode: "rotation", Cadence: "jours-2", BestOf: 3}},
+		{url.Values{"difficulty": {"expert"}, "serie": {"4"}, "cadence": {"0+5"}},
+			RoomSettings{Difficulty: "easy", Mode: "normal", BestOf: 1}},
+	}
+	for _, tt := range tests {
+		if got := parseRoomSettings(postForm(tt.form)); got != tt.want {
+			t.Errorf("parseRoomSettings(%v) = %+v, attendu %+v", tt.form, got, tt.want)
+		}
+	}
+}
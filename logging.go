@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// requestIDHeader transmet l'identifiant de requête: repris d'un proxy de confiance s'il est fourni, renvoyé au client.
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// withRequestID attache l'identifiant de la requête à son contexte. L'identifiant de l'en-tête n'est repris
+// que d'un proxy de confiance et s'il est valide: un client ne peut ni le forger ni en réutiliser un autre.
+func withRequestID(r *http.Request) (*http.Request, string) {
+	id := r.Header.Get(requestIDHeader)
+	if !validRequestID(id) || !fromTrustedProxy(r) {
+		id = newID()
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)), id
+}
+
+// validRequestID vérifie qu'un identifiant de requête reçu est court et limité à [A-Za-z0-9-].
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// fromTrustedProxy indique si la requête arrive directement d'un proxy de confiance.
+func fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && trustedProxy(addr)
+}
+
+// logger retourne le journal de la requête du contexte (identifiant de requête compris), ou le journal par défaut.
+func logger(ctx context.Context) *slog.Logger {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// newLogHandler retourne le format du journal: "json" (une ligne JSON par entrée) ou "text".
+func newLogHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	if format == "text" {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// aiMoveInfo décrit le calcul d'un coup de l'IA, pour le journal.
+type aiMoveInfo struct {
+	Ply      int    // rang du coup dans l'historique
+	Source   string // "recherche", "livre", "hasard" ou nom du moteur externe
+	Depth    int
+	Score    int
+	Duration time.Duration
+}
+
+// logEvents journalise les changements de la partie depuis le dernier appel: création, coups (avec la
+// recherche des coups de l'IA), fin et réinitialisation d'une partie inachevée. Appelé avec s.mu verrouillé.
+func (s *GameSession) logEvents(ctx context.Context) {
+	log := logger(ctx).With("game", s.ID)
+	g := s.Game
+	if old := s.loggedGame; old != g {
+		if old != nil && !old.GameOver {
+			log.Info("partie réinitialisée", "moves", len(old.History), "replaced", g != nil)
+		}
+		s.loggedGame, s.loggedMoves = g, 0
+		if g != nil {
+			log.Info("partie créée", "room", s.Room != nil, "mode", g.Mode, "difficulty", g.Difficulty,
+				"rows", g.Rows, "cols", g.Cols, "opponent", opponentKind(g), "ai_level", g.AILevel.String(),
+				"cadence", g.TimeControl.Name, "players", []string{g.Username1, g.Username2})
+		}
+	}
+	if g == nil {
+		return
+	}
+	for i := s.loggedMoves; i < len(g.History); i++ {
+		mv := g.History[i]
+		if ai := g.lastAI; ai != nil && ai.Ply == i {
+			log.Info("coup de l'IA", "ply", i+1, "player", mv.Player, "slot", mv.Slot, "row", mv.Row, "col", mv.Col,
+				"source", ai.Source, "depth", ai.Depth, "score", ai.Score, "duration", ai.Duration)
+			continue
+		}
+		log.Info("coup", "ply", i+1, "player", mv.Player, "slot", mv.Slot, "row", mv.Row, "col", mv.Col)
+	}
+	s.loggedMoves = len(g.History)
+	if g.GameOver && s.loggedOver != g {
+		s.loggedOver = g
+		log.Info("partie terminée", "winner", g.Winner, "moves", len(g.History), "timed_out", g.TimedOut, "forfeited", g.Forfeited)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	defer func(saved []netip.Prefix) { trustedProxies = saved }(trustedProxies)
+	trustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name   string
+		remote string
+		header string
+		kept   bool
+	}{
+		{"proxy de confiance", "10.0.0.1:1234", "abc-123-DEF", true},
+		{"client direct", "203.0.113.9:1234", "abc-123-DEF", false},
+		{"caractères interdits", "10.0.0.1:1234", "abc 123\n", false},
+		{"identifiant trop long", "10.0.0.1:1234", strings.Repeat("a", 65), false},
+		{"sans en-tête", "10.0.0.1:1234", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.header != "" {
+			r.Header.Set(requestIDHeader, tt.header)
+		}
+		r, id := withRequestID(r)
+		if (id == tt.header) != tt.kept || !validRequestID(id) {
+			t.Errorf("%s: identifiant %q (en-tête %q)", tt.name, id, tt.header)
+		}
+		if got, _ := r.Context().Value(requestIDKey{}).(string); got != id {
+			t.Errorf("%s: identifiant du contexte %q, attendu %q", tt.name, got, id)
+		}
+	}
+}
+
+// captureLog redirige le journal par défaut vers un tampon le temps du test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	saved := slog.Default()
+	slog.SetDefault(slog.New(newLogHandler(&buf, "json")))
+	t.Cleanup(func() { slog.SetDefault(saved) })
+	return &buf
+}
+
+// logEntries décode les entrées JSON du journal.
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("ligne du journal %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestRequestIDInResponseAndLog(t *testing.T) {
+	buf := captureLog(t)
+	h := instrument("test", func(w http.ResponseWriter, r *http.Request) {
+		logger(r.Context()).Info("dans la page")
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/", nil))
+	id := w.Header().Get(requestIDHeader)
+	entries := logEntries(t, buf)
+	if id == "" || len(entries) != 2 {
+		t.Fatalf("identifiant %q, %d entrées", id, len(entries))
+	}
+	for _, e := range entries {
+		if e["request_id"] != id {
+			t.Errorf("entrée %q: request_id %v, attendu %q", e["msg"], e["request_id"], id)
+		}
+	}
+}
+
+func TestLogEvents(t *testing.T) {
+	buf := captureLog(t)
+	messages := func() []string {
+		var msgs []string
+		for _, e := range logEntries(t, buf) {
+			msgs = append(msgs, e["msg"].(string))
+		}
+		return msgs
+	}
+	ctx := context.Background()
+	s := &GameSession{ID: "abc"}
+	s.Game = newTestGame("normal")
+	s.logEvents(ctx)
+	if got := messages(); !slices.Equal(got, []string{"partie créée"}) {
+		t.Fatalf("création: %v", got)
+	}
+
+	g := s.Game
+	g.DropToken(3)
+	g.DropToken(4)
+	g.lastAI = &aiMoveInfo{Ply: 1, Source: "recherche", Depth: 4}
+	s.logEvents(ctx)
+	if got := messages(); !slices.Equal(got, []string{"coup", "coup de l'IA"}) {
+		t.Fatalf("coups: %v", got)
+	}
+	// Rien de nouveau: rien n'est journalisé
+	s.logEvents(ctx)
+	if got := messages(); len(got) != 0 {
+		t.Fatalf("entrées en double: %v", got)
+	}
+
+	// Une partie inachevée remplacée, puis une partie terminée
+	s.Game = newTestGame("normal")
+	s.logEvents(ctx)
+	if got := messages(); !slices.Equal(got, []string{"partie réinitialisée", "partie créée"}) {
+		t.Fatalf("remplacement: %v", got)
+	}
+	s.Game.finish(2)
+	s.logEvents(ctx)
+	s.logEvents(ctx)
+	if got := messages(); !slices.Equal(got, []string{"partie terminée"}) {
+		t.Fatalf("fin: %v", got)
+	}
+}
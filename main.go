@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 )
 
@@ -15,8 +19,28 @@ type Gravity int
 const (
 	GravityDown Gravity = iota
 	GravityUp
+	GravityLeft
+	GravityRight
 )
 
+// rotationOrder donne l'ordre des directions en mode "rotation".
+var rotationOrder = []Gravity{GravityDown, GravityLeft, GravityUp, GravityRight}
+
+// Horizontal indique si les jetons glissent le long d'une ligne (on choisit alors une ligne, pas une colonne).
+func (gr Gravity) Horizontal() bool {
+	return gr == GravityLeft || gr == GravityRight
+}
+
+// next retourne la direction suivante dans le cycle du mode rotation.
+func (gr Gravity) next() Gravity {
+	for i, d := range rotationOrder {
+		if d == gr {
+			return rotationOrder[(i+1)%len(rotationOrder)]
+		}
+	}
+	return GravityDown
+}
+
 type GameMode int
 
 const (
@@ -32,32 +56,53 @@ const (
 	AIHard
 )
 
+// Move est un coup de l'historique: l'emplacement choisi et la case où le jeton s'est posé.
+type Move struct {
+	Player   int
+	Slot     int
+	Row, Col int
+}
+
 // Ajoute un champ Mode à Game pour retenir le mode de jeu
 type Game struct {
-	Board         [][]int
-	Rows, Cols    int
-	CurrentPlayer int
-	Winner        int
-	GameOver      bool
-	LastRow       int
-	LastCol       int
-	TurnCount     int
-	Gravity       Gravity
-	Difficulty    string
-	Username      string // kept for backward compatibility
-	Username1     string
-	Username2     string
-	Mode          string // "normal" ou "inverse"
-	GameMode      GameMode
-	AILevel       AILevel
-	Skin          string // Nom du skin sélectionné
+	Board          [][]int
+	Rows, Cols     int
+	CurrentPlayer  int
+	Winner         int
+	GameOver       bool
+	LastRow        int
+	LastCol        int
+	TurnCount      int
+	Gravity        Gravity
+	Difficulty     string
+	Username       string // kept for backward compatibility
+	Username1      string
+	Username2      string
+	Mode           string // "normal", "inverse", "gauche", "droite" ou "rotation"
+	GameMode       GameMode
+	AILevel        AILevel
+	Profile        *AIProfile // profil de l'IA (nil: profil par défaut du niveau)
+	Opponent       Bot        `json:"-"` // moteur externe ou bot HTTP jouant l'IA (nil: IA interne)
+	Forfeited      bool       // partie perdue par forfait du joueur automatique
+	Skin           string     // Nom du skin sélectionné
+	TimeControl    TimeControl
+	Remaining      [2]time.Duration // temps restant des joueurs 1 et 2
+	TurnStart      time.Time        // début de la réflexion du joueur qui a le trait
+	TimedOut       bool             // partie perdue au temps
+	Match          *Match           // série en cours (nil pour une partie simple)
+	History        []Move           // coups joués, dans l'ordre
+	InitialBoard   [][]int          // plateau de départ (pré-remplissage compris), pour rejouer la partie
+	InitialGravity Gravity
+	Chat           [2][]ChatMessage // discussions des joueurs et des spectateurs
+	ChatSeq        [2]int           // numéro du dernier message de chaque canal
+	SeatTokens     [2]string        // jetons des places 1 et 2, exigés pour jouer un coup
+
+	searchDeadline time.Time // limite de réflexion de la recherche en cours
+	searchAborted  bool
+	searchNodes    int64       // positions visitées par la recherche
+	lastAI         *aiMoveInfo // calcul du dernier coup de l'IA, pour le journal
 }
 
-var (
-	game  *Game
-	mutex sync.Mutex
-)
-
 func NewGame(rows, cols, prefill int, difficulty, username1, username2, mode, skin string, gameMode GameMode, aiLevel AILevel) *Game {
 	board := make([][]int, rows)
 	for i := range board {
@@ -75,80 +120,175 @@ func NewGame(rows, cols, prefill int, difficulty, username1, username2, mode, sk
 		}
 	}
 	gravity := GravityDown
-	if mode == "inverse" {
+	switch mode {
+	case "inverse":
 		gravity = GravityUp
-	} else {
+	case "gauche":
+		gravity = GravityLeft
+	case "droite":
+		gravity = GravityRight
+	default:
 		gravity = GravityDown
 	}
 	if gameMode == ModeHumanVsAI && username2 == "" {
 		username2 = "IA"
 	}
+	initial := make([][]int, rows)
+	for i := range board {
+		initial[i] = append([]int(nil), board[i]...)
+	}
 	return &Game{
-		Board:         board,
-		Rows:          rows,
-		Cols:          cols,
-		CurrentPlayer: 1,
-		Winner:        0,
-		GameOver:      false,
-		LastRow:       -1,
-		LastCol:       -1,
-		TurnCount:     0,
-		Gravity:       gravity,
-		Difficulty:    difficulty,
-		Username:      username1,
-		Username1:     username1,
-		Username2:     username2,
-		Mode:          mode,
-		GameMode:      gameMode,
-		AILevel:       aiLevel,
-		Skin:          skin,
-	}
-}
-
-// DropToken now supports gravity direction and increments turn count.
-func (g *Game) DropToken(col int) bool {
-	if col < 0 || col >= g.Cols || g.GameOver {
-		return false
+		Board:          board,
+		Rows:           rows,
+		Cols:           cols,
+		CurrentPlayer:  1,
+		Winner:         0,
+		GameOver:       false,
+		LastRow:        -1,
+		LastCol:        -1,
+		TurnCount:      0,
+		Gravity:        gravity,
+		Difficulty:     difficulty,
+		Username:       username1,
+		Username1:      username1,
+		Username2:      username2,
+		Mode:           mode,
+		GameMode:       gameMode,
+		AILevel:        aiLevel,
+		Skin:           skin,
+		InitialBoard:   initial,
+		InitialGravity: gravity,
+		SeatTokens:     [2]string{newID(), newID()},
+	}
+}
+
+// clone retourne une copie indépendante de la partie, utilisable par la recherche sans verrou.
+// La série et la pendule ne sont pas partagées.
+func (g *Game) clone() *Game {
+	c := *g
+	c.Board = make([][]int, g.Rows)
+	for r := range g.Board {
+		c.Board[r] = append([]int(nil), g.Board[r]...)
 	}
-	var row int
-	if g.Gravity == GravityDown {
+	c.History = append([]Move(nil), g.History...)
+	c.Match = nil
+	c.TimeControl = TimeControl{}
+	c.searchDeadline = time.Time{}
+	c.searchAborted = false
+	c.searchNodes = 0
+	c.lastAI = nil
+	return &c
+}
+
+// landingCell retourne la case où tomberait un jeton joué sur l'emplacement slot
+// (une colonne en gravité verticale, une ligne en gravité latérale), ou ok=false si l'emplacement est plein.
+func (g *Game) landingCell(slot int) (row, col int, ok bool) {
+	switch g.Gravity {
+	case GravityDown:
+		if slot < 0 || slot >= g.Cols {
+			return -1, -1, false
+		}
 		for row = g.Rows - 1; row >= 0; row-- {
-			if g.Board[row][col] == 0 {
-				break
+			if g.Board[row][slot] == 0 {
+				return row, slot, true
 			}
 		}
-	} else {
+	case GravityUp:
+		if slot < 0 || slot >= g.Cols {
+			return -1, -1, false
+		}
 		for row = 0; row < g.Rows; row++ {
-			if g.Board[row][col] == 0 {
-				break
+			if g.Board[row][slot] == 0 {
+				return row, slot, true
+			}
+		}
+	case GravityLeft:
+		if slot < 0 || slot >= g.Rows {
+			return -1, -1, false
+		}
+		for col = 0; col < g.Cols; col++ {
+			if g.Board[slot][col] == 0 {
+				return slot, col, true
 			}
 		}
+	case GravityRight:
+		if slot < 0 || slot >= g.Rows {
+			return -1, -1, false
+		}
+		for col = g.Cols - 1; col >= 0; col-- {
+			if g.Board[slot][col] == 0 {
+				return slot, col, true
+			}
+		}
+	}
+	return -1, -1, false
+}
+
+// slotCount retourne le nombre d'emplacements jouables selon la gravité (colonnes ou lignes).
+func (g *Game) slotCount() int {
+	if g.Gravity.Horizontal() {
+		return g.Rows
 	}
-	if row < 0 || row >= g.Rows || g.Board[row][col] != 0 {
+	return g.Cols
+}
+
+// DropToken joue sur l'emplacement slot (colonne, ou ligne en gravité latérale) et incrémente le nombre de tours.
+func (g *Game) DropToken(slot int) bool {
+	if g.GameOver || g.checkTimeout() {
+		return false
+	}
+	row, col, ok := g.landingCell(slot)
+	if !ok {
 		return false
 	}
 	g.Board[row][col] = g.CurrentPlayer
 	g.LastRow = row
 	g.LastCol = col
 	g.TurnCount++
-	// Gravity reversal every 5 turns - only in inverse mode
-	if g.Mode == "inverse" && g.TurnCount%5 == 0 {
-		if g.Gravity == GravityDown {
-			g.Gravity = GravityUp
-		} else {
-			g.Gravity = GravityDown
+	g.History = append(g.History, Move{Player: g.CurrentPlayer, Slot: slot, Row: row, Col: col})
+	g.punchClock(g.CurrentPlayer)
+	// Changement de gravité tous les 5 tours - uniquement en mode inverse ou rotation
+	if g.TurnCount%5 == 0 {
+		switch g.Mode {
+		case "inverse":
+			if g.Gravity == GravityDown {
+				g.Gravity = GravityUp
+			} else {
+				g.Gravity = GravityDown
+			}
+		case "rotation":
+			g.Gravity = g.Gravity.next()
 		}
 	}
 	if g.checkWin(row, col) {
-		g.Winner = g.CurrentPlayer
-		g.GameOver = true
+		g.finish(g.CurrentPlayer)
 	} else if g.isDraw() {
-		g.GameOver = true
+		g.finish(0)
 	}
 	g.CurrentPlayer = 3 - g.CurrentPlayer
 	return true
 }
 
+// matchBestOf retourne le format de la série de la partie (1 pour une partie simple).
+func (g *Game) matchBestOf() int {
+	if g.Match == nil {
+		return 1
+	}
+	return g.Match.BestOf
+}
+
+// finish termine la partie avec le vainqueur donné (0 pour un match nul) et met à jour la série.
+func (g *Game) finish(winner int) {
+	if g.GameOver {
+		return
+	}
+	g.Winner = winner
+	g.GameOver = true
+	if g.Match != nil {
+		g.Match.Record(winner)
+	}
+}
+
 // checkWin vérifie si le dernier coup joué (row, col) crée un alignement de 4 jetons de même couleur.
 func (g *Game) checkWin(row, col int) bool {
 	player := g.Board[row][col]
@@ -180,11 +320,14 @@ func (g *Game) checkWin(row, col int) bool {
 	return false
 }
 
-// isDraw vérifie si le plateau est plein (aucune case vide en haut de chaque colonne).
+// isDraw vérifie si le plateau est plein. Un jeton tombe sur la case libre la plus proche du bord
+// de la gravité: tant qu'il reste une case libre, son emplacement est jouable quelle que soit la gravité.
 func (g *Game) isDraw() bool {
-	for c := 0; c < g.Cols; c++ {
-		if g.Board[0][c] == 0 {
-			return false
+	for _, row := range g.Board {
+		for _, cell := range row {
+			if cell == 0 {
+				return false
+			}
 		}
 	}
 	return true
@@ -192,43 +335,22 @@ func (g *Game) isDraw() bool {
 
 // AI Functions
 
-// getValidMoves retourne les colonnes où il est possible de jouer
+// getValidMoves retourne les emplacements (colonnes ou lignes) où il est possible de jouer
 func (g *Game) getValidMoves() []int {
 	var moves []int
-	for col := 0; col < g.Cols; col++ {
-		// Vérifie si la colonne n'est pas pleine
-		var canPlay bool
-		if g.Gravity == GravityDown {
-			canPlay = g.Board[0][col] == 0
-		} else {
-			canPlay = g.Board[g.Rows-1][col] == 0
-		}
-		if canPlay {
-			moves = append(moves, col)
+	for slot := 0; slot < g.slotCount(); slot++ {
+		// Mêmes règles que DropToken: l'emplacement est jouable s'il reste une case d'arrivée
+		if _, _, ok := g.landingCell(slot); ok {
+			moves = append(moves, slot)
 		}
 	}
 	return moves
 }
 
-// checkWinningMove vérifie si jouer dans une colonne ferait gagner le joueur
-func (g *Game) checkWinningMove(col, player int) bool {
-	// Simule le coup
-	var row int
-	if g.Gravity == GravityDown {
-		for row = g.Rows - 1; row >= 0; row-- {
-			if g.Board[row][col] == 0 {
-				break
-			}
-		}
-	} else {
-		for row = 0; row < g.Rows; row++ {
-			if g.Board[row][col] == 0 {
-				break
-			}
-		}
-	}
-
-	if row < 0 || row >= g.Rows || g.Board[row][col] != 0 {
+// checkWinningMove vérifie si jouer sur un emplacement ferait gagner le joueur
+func (g *Game) checkWinningMove(slot, player int) bool {
+	row, col, ok := g.landingCell(slot)
+	if !ok {
 		return false
 	}
 
@@ -240,60 +362,22 @@ func (g *Game) checkWinningMove(col, player int) bool {
 	return win
 }
 
-// aiEasyMove - IA facile : joue aléatoirement
-func (g *Game) aiEasyMove() int {
-	moves := g.getValidMoves()
-	if len(moves) == 0 {
-		return -1
-	}
-	return moves[rand.Intn(len(moves))]
-}
-
-// aiMediumMove - IA moyenne : bloque les victoires adverses et cherche ses victoires
-func (g *Game) aiMediumMove() int {
-	moves := g.getValidMoves()
-	if len(moves) == 0 {
-		return -1
-	}
-
-	// 1. Cherche un coup gagnant pour l'IA (joueur 2)
-	for _, col := range moves {
-		if g.checkWinningMove(col, 2) {
-			return col
-		}
-	}
-
-	// 2. Bloque un coup gagnant de l'adversaire (joueur 1)
-	for _, col := range moves {
-		if g.checkWinningMove(col, 1) {
-			return col
-		}
-	}
-
-	// 3. Sinon, joue aléatoirement
-	return moves[rand.Intn(len(moves))]
-}
-
-// aiHardMove - IA difficile : utilise minimax
-func (g *Game) aiHardMove() int {
-	moves := g.getValidMoves()
-	if len(moves) == 0 {
-		return -1
-	}
-
-	// Utilise minimax avec une profondeur limitée
-	_, bestCol := g.minimax(4, true, -1000, 1000)
+// Scores de recherche: une victoire vaut winScore (plus la profondeur restante, pour préférer les gains rapides),
+// bien au-delà de ce que peut donner evaluateBoard.
+const (
+	winScore = 100000
+	infScore = 1000000
+)
 
-	// Fallback au cas où minimax échoue
-	if bestCol == -1 && len(moves) > 0 {
-		return moves[0]
+// minimax - Algorithme minimax avec élagage alpha-beta (le joueur 2 maximise)
+func (g *Game) minimax(depth int, isMaximizing bool, alpha, beta int) (int, int) {
+	g.searchNodes++
+	// Temps de réflexion épuisé: le résultat de cette itération sera ignoré
+	if !g.searchDeadline.IsZero() && time.Now().After(g.searchDeadline) {
+		g.searchAborted = true
+		return 0, -1
 	}
 
-	return bestCol
-}
-
-// minimax - Algorithme minimax avec élagage alpha-beta
-func (g *Game) minimax(depth int, isMaximizing bool, alpha, beta int) (int, int) {
 	// Conditions de fin
 	if depth == 0 || g.GameOver {
 		return g.evaluateBoard(), -1
@@ -307,16 +391,21 @@ func (g *Game) minimax(depth int, isMaximizing bool, alpha, beta int) (int, int)
 	bestCol := moves[0]
 
 	if isMaximizing {
-		maxEval := -1000
+		maxEval := -infScore
 		for _, col := range moves {
 			// Simule le coup
-			row := g.simulateMove(col, 2)
+			row, cell := g.simulateMove(col, 2)
 			if row == -1 {
 				continue
 			}
 
-			eval, _ := g.minimax(depth-1, false, alpha, beta)
-			g.Board[row][col] = 0 // Annule le coup
+			var eval int
+			if g.checkWin(row, cell) {
+				eval = winScore + depth
+			} else {
+				eval, _ = g.minimax(depth-1, false, alpha, beta)
+			}
+			g.Board[row][cell] = 0 // Annule le coup
 
 			if eval > maxEval {
 				maxEval = eval
@@ -330,16 +419,21 @@ func (g *Game) minimax(depth int, isMaximizing bool, alpha, beta int) (int, int)
 		}
 		return maxEval, bestCol
 	} else {
-		minEval := 1000
+		minEval := infScore
 		for _, col := range moves {
 			// Simule le coup
-			row := g.simulateMove(col, 1)
+			row, cell := g.simulateMove(col, 1)
 			if row == -1 {
 				continue
 			}
 
-			eval, _ := g.minimax(depth-1, true, alpha, beta)
-			g.Board[row][col] = 0 // Annule le coup
+			var eval int
+			if g.checkWin(row, cell) {
+				eval = -(winScore + depth)
+			} else {
+				eval, _ = g.minimax(depth-1, true, alpha, beta)
+			}
+			g.Board[row][cell] = 0 // Annule le coup
 
 			if eval < minEval {
 				minEval = eval
@@ -355,29 +449,15 @@ func (g *Game) minimax(depth int, isMaximizing bool, alpha, beta int) (int, int)
 	}
 }
 
-// simulateMove simule un coup sans vérifier les conditions de victoire
-func (g *Game) simulateMove(col, player int) int {
-	var row int
-	if g.Gravity == GravityDown {
-		for row = g.Rows - 1; row >= 0; row-- {
-			if g.Board[row][col] == 0 {
-				break
-			}
-		}
-	} else {
-		for row = 0; row < g.Rows; row++ {
-			if g.Board[row][col] == 0 {
-				break
-			}
-		}
-	}
-
-	if row < 0 || row >= g.Rows || g.Board[row][col] != 0 {
-		return -1
+// simulateMove simule un coup sans vérifier les conditions de victoire et retourne la case jouée (-1, -1 si impossible)
+func (g *Game) simulateMove(slot, player int) (int, int) {
+	row, col, ok := g.landingCell(slot)
+	if !ok {
+		return -1, -1
 	}
 
 	g.Board[row][col] = player
-	return row
+	return row, col
 }
 
 // evaluateBoard évalue la position pour l'IA (joueur 2)
@@ -431,22 +511,24 @@ func (g *Game) evaluateWindow(startR, startC, deltaR, deltaC int) int {
 		return 0
 	}
 
+	weights := g.evalWeights()
+
 	// Évaluation pour l'IA (joueur 2)
 	if aiCount == 4 {
-		score += 100
+		score += weights.Four
 	} else if aiCount == 3 {
-		score += 10
+		score += weights.Three
 	} else if aiCount == 2 {
-		score += 2
+		score += weights.Two
 	}
 
 	// Évaluation contre l'humain (joueur 1)
 	if humanCount == 4 {
-		score -= 100
+		score -= weights.Four
 	} else if humanCount == 3 {
-		score -= 10
+		score -= weights.Three
 	} else if humanCount == 2 {
-		score -= 2
+		score -= weights.Two
 	}
 
 	return score
@@ -467,26 +549,50 @@ func min(a, b int) int {
 	return b
 }
 
-// aiMove choisit le coup de l'IA selon son niveau
-func (g *Game) aiMove() int {
-	switch g.AILevel {
-	case AIEasy:
-		return g.aiEasyMove()
-	case AIMedium:
-		return g.aiMediumMove()
-	case AIHard:
-		return g.aiHardMove()
-	default:
-		return g.aiEasyMove()
+// aiMove choisit le coup de l'IA: celui du joueur externe s'il y en a un,
+// sinon (ou en cas d'échec du moteur) celui de son profil (par défaut celui de son niveau).
+// Un bot qui déclare forfait retourne errForfeit.
+func (g *Game) aiMove() (int, error) {
+	if g.Opponent != nil {
+		slot, err := g.Opponent.Move(g)
+		if err == nil {
+			return slot, nil
+		}
+		if errors.Is(err, errForfeit) {
+			return -1, err
+		}
+		slog.Warn("erreur moteur, repli sur l'IA interne", "bot", g.Opponent.Name(), "err", err)
 	}
+	return g.profileMove(g.aiProfile()), nil
+}
+
+// aiToMove indique si l'IA a le trait.
+func (g *Game) aiToMove() bool {
+	return g != nil && g.GameMode == ModeHumanVsAI && !g.GameOver && g.CurrentPlayer == 2
 }
 
 func (g *Game) playAIMoveIfNeeded() bool {
-	if g == nil || g.GameMode != ModeHumanVsAI || g.GameOver || g.CurrentPlayer != 2 {
+	if !g.aiToMove() {
 		return false
 	}
 
-	aiCol := g.aiMove()
+	start := time.Now()
+	g.lastAI = nil
+	aiCol, err := g.aiMove()
+	elapsed := time.Since(start)
+	aiMoveSeconds.observe(elapsed, g.aiName())
+	if g.lastAI == nil && g.Opponent != nil {
+		g.lastAI = &aiMoveInfo{Source: g.Opponent.Name()}
+	}
+	if g.lastAI != nil {
+		g.lastAI.Ply, g.lastAI.Duration = len(g.History), elapsed
+	}
+	if errors.Is(err, errForfeit) {
+		slog.Warn("forfait du bot", "bot", g.Opponent.Name(), "err", err)
+		g.Forfeited = true
+		g.finish(3 - g.CurrentPlayer)
+		return false
+	}
 	if aiCol < 0 {
 		return false
 	}
@@ -526,16 +632,42 @@ func (g *Game) getWinningPositions() [][2]int {
 	return nil
 }
 
+// boardView décrit ce que le visiteur peut faire depuis le plateau.
+type boardView struct {
+	CanPlay    bool   // le visiteur a le trait: le clic sur le plateau est actif
+	Hints      bool   // bouton d'indice (parties locales uniquement)
+	Rematch    bool   // bouton de revanche en fin de partie
+	AnalyseURL string // lien d'analyse en fin de partie
+	ResetLabel string // bouton de sortie ("Nouvelle partie", "Abandonner", ...); vide pour un spectateur
+	CSRF       string // jeton anti-CSRF du visiteur, renvoyé par le formulaire
+	SeatToken  string // jeton de la place du visiteur, exigé pour jouer un coup
+}
+
+// soloView retourne les actions d'une partie locale: le clic est désactivé pendant le tour de l'IA.
+// À deux sur le même écran, le formulaire porte le jeton de la place qui a le trait.
+func soloView(g *Game, p *Player) boardView {
+	view := boardView{
+		CanPlay:    !g.GameOver && !(g.GameMode == ModeHumanVsAI && g.CurrentPlayer == 2),
+		Hints:      true,
+		Rematch:    true,
+		AnalyseURL: appPath("/analyse"),
+		ResetLabel: "Nouvelle partie",
+		CSRF:       p.csrfToken(),
+	}
+	if view.CanPlay {
+		view.SeatToken = g.seatToken(g.CurrentPlayer)
+	}
+	return view
+}
+
 // renderBoard génère le HTML du plateau et permet la sélection de colonne par clic sur la flèche au-dessus de chaque colonne.
 // Les boutons de colonne ont été remplacés par cette interaction directe, plus intuitive.
-func renderBoard(g *Game) template.HTML {
+func renderBoard(g *Game, view boardView) template.HTML {
 	playerClass := "p1"
 	if g.CurrentPlayer == 2 {
 		playerClass = "p2"
 	}
 
-	// Désactive l'interface si c'est le tour de l'IA
-	disableInterface := g.GameMode == ModeHumanVsAI && g.CurrentPlayer == 2 && !g.GameOver
 	// Plus de flèches directionnelles: clic direct sur la colonne
 	winning := map[[2]int]bool{}
 	if g.GameOver && g.Winner != 0 {
@@ -543,11 +675,29 @@ func renderBoard(g *Game) template.HTML {
 			winning[pos] = true
 		}
 	}
-	html := "<form method='POST' id='board-form'><input type='hidden' name='col' id='col-input'/>\n"
+	// En gravité latérale on sélectionne une ligne au lieu d'une colonne
+	slotAttr := "data-col"
+	slotName := "col"
+	if g.Gravity.Horizontal() {
+		slotAttr = "data-row"
+		slotName = "row"
+	}
+	html := "<form method='POST' id='board-form'><input type='hidden' name='" + slotName + "' id='col-input'/>\n"
+	if view.CSRF != "" {
+		html += "<input type='hidden' name='" + csrfField + "' value='" + view.CSRF + "'/>"
+	}
+	if view.SeatToken != "" {
+		html += "<input type='hidden' name='" + seatTokenField + "' value='" + view.SeatToken + "'/>\n"
+	}
 	html += "<div class='board-wrap " + playerClass
-	if g.Gravity == GravityUp {
+	switch g.Gravity {
+	case GravityUp:
 		html += " gravity-up"
-	} else {
+	case GravityLeft:
+		html += " gravity-left"
+	case GravityRight:
+		html += " gravity-right"
+	default:
 		html += " gravity-down"
 	}
 	html += "' id='board-wrap' style='overflow-x:auto; max-width:100vw;'>\n"
@@ -557,7 +707,7 @@ func renderBoard(g *Game) template.HTML {
 	} else {
 		html += "0'"
 	}
-	html += " data-current='" + strconv.Itoa(g.CurrentPlayer) + "' style='margin:auto;'>\n"
+	html += " data-current='" + strconv.Itoa(g.CurrentPlayer) + "' data-slot='" + slotAttr + "' style='margin:auto;'>\n"
 
 	// Suppression de la ligne de sélection: on clique désormais directement sur une colonne du plateau
 
@@ -580,31 +730,41 @@ func renderBoard(g *Game) template.HTML {
 			case 2:
 				cell = "<div class='token-wrap" + wrapCls + "'><div class='token yellow" + tokenCls + "'></div></div>"
 			}
-			html += "<td data-col='" + strconv.Itoa(c) + "'>" + cell + "</td>"
+			html += "<td data-col='" + strconv.Itoa(c) + "' data-row='" + strconv.Itoa(r) + "'>" + cell + "</td>"
 		}
 		html += "</tr>"
 	}
 	html += "</table>\n"
 	html += "</div>" // end board-wrap
-	html += "<div class='controls'><button name='reset' value='1'>Nouvelle partie</button>"
+	html += "<div class='controls'>"
+	if view.ResetLabel != "" {
+		html += "<button name='reset' value='1'>" + view.ResetLabel + "</button>"
+	}
+	if view.CanPlay && view.Hints {
+		html += "<button type='button' id='hint-btn'>Indice</button>"
+	}
 	if g.GameOver {
-		html += "<button name='rematch' value='1'>Revanche</button>"
+		if view.Rematch {
+			html += "<button name='rematch' value='1'>" + rematchLabel(g) + "</button>"
+		}
+		html += "<a class='button-link' href='" + view.AnalyseURL + "'>Analyser la partie</a>"
 	}
 	html += "</div></form>"
 
-	// JS pour gérer le clic directement sur une colonne du plateau et la surbrillance au survol
-	if !g.GameOver && !disableInterface {
+	// JS pour gérer le clic directement sur une colonne (ou une ligne) du plateau et la surbrillance au survol
+	if view.CanPlay {
 		html += `<script>
 		(function(){
 			var form = document.getElementById('board-form');
 			var colInput = document.getElementById('col-input');
+			var slotAttr = document.getElementById('board').getAttribute('data-slot') || 'data-col';
 			function setColHighlight(col, on){
-				document.querySelectorAll('#board td[data-col="' + col + '"]').forEach(function(td){
+				document.querySelectorAll('#board td[' + slotAttr + '="' + col + '"]').forEach(function(td){
 					if(on){ td.classList.add('col-selected'); } else { td.classList.remove('col-selected'); }
 				});
 			}
 			document.querySelectorAll('#board td').forEach(function(td){
-				var col = td.getAttribute('data-col');
+				var col = td.getAttribute(slotAttr);
 				if(col === null) return;
 				td.addEventListener('mouseenter', function(){ setColHighlight(col, true); });
 				td.addEventListener('mouseleave', function(){ setColHighlight(col, false); });
@@ -613,6 +773,27 @@ func renderBoard(g *Game) template.HTML {
 					form.submit();
 				});
 			});
+			// Indice: évaluation de chaque coup affichée au bord du plateau
+			var hintBtn = document.getElementById('hint-btn');
+			if(hintBtn){
+				hintBtn.addEventListener('click', function(){
+					hintBtn.disabled = true;
+					fetch('` + appPath("/hint") + `').then(function(res){ return res.status === 200 ? res.json() : null; }).then(function(data){
+						hintBtn.disabled = false;
+						document.querySelectorAll('#board .hint-badge').forEach(function(b){ b.remove(); });
+						if(!data || !data.moves) return;
+						data.moves.forEach(function(m){
+							var td = document.querySelector('#board td[' + slotAttr + '="' + m.slot + '"]');
+							if(!td) return;
+							var badge = document.createElement('span');
+							badge.className = 'hint-badge ' + m.verdict + (m.slot === data.best ? ' hint-best' : '');
+							badge.textContent = m.verdict === 'gagnant' ? 'G' : m.verdict === 'perdant' ? 'P' : m.score;
+							badge.title = m.verdict + ' (' + m.score + ', profondeur ' + data.depth + ')';
+							td.appendChild(badge);
+						});
+					}).catch(function(){ hintBtn.disabled = false; });
+				});
+			}
 		})();
 		</script>`
 	}
@@ -620,249 +801,469 @@ func renderBoard(g *Game) template.HTML {
 	return template.HTML(html)
 }
 
+// rematchLabel retourne le libellé du bouton de revanche selon l'état de la série.
+func rematchLabel(g *Game) string {
+	if g.Match == nil {
+		return "Revanche"
+	}
+	if g.Match.Over() {
+		return "Nouvelle série"
+	}
+	return "Partie suivante"
+}
+
 // --- Template loading ---
 var (
-	pageTmpl  *template.Template
-	startTmpl *template.Template
-	winTmpl   *template.Template
-	loseTmpl  *template.Template
-	modeTmpl  *template.Template
+	pageTmpl  = &siteTemplate{name: "templates/game.html"}
+	startTmpl = &siteTemplate{name: "templates/start.html"}
+	winTmpl   = &siteTemplate{name: "templates/win.html"}
+	loseTmpl  = &siteTemplate{name: "templates/lose.html"}
+	modeTmpl  = &siteTemplate{name: "templates/mode.html"}
+
+	analysisTmpl = &siteTemplate{name: "templates/analysis.html"}
+	arenaTmpl    = &siteTemplate{name: "templates/arena.html"}
+	lobbyTmpl    = &siteTemplate{name: "templates/lobby.html"}
+	myGamesTmpl  = &siteTemplate{name: "templates/games.html"}
+	adminTmpl    = &siteTemplate{name: "templates/admin.html"}
+	errorTmpl    = &siteTemplate{name: "templates/error.html"}
+
+	siteTemplates = []*siteTemplate{pageTmpl, startTmpl, winTmpl, loseTmpl, modeTmpl, analysisTmpl, arenaTmpl, lobbyTmpl, myGamesTmpl, adminTmpl, errorTmpl}
 )
 
+// loadTemplates lit tous les templates, pour signaler une erreur dès le démarrage.
 func loadTemplates() error {
-	var err error
-	pageTmpl, err = template.ParseFiles("templates/game.html")
-	if err != nil {
-		return err
-	}
-	startTmpl, err = template.ParseFiles("templates/start.html")
-	if err != nil {
-		return err
-	}
-	winTmpl, err = template.ParseFiles("templates/win.html")
-	if err != nil {
-		return err
-	}
-	loseTmpl, err = template.ParseFiles("templates/lose.html")
-	if err != nil {
-		return err
+	for _, t := range siteTemplates {
+		if err := t.load(); err != nil {
+			return err
+		}
 	}
-	modeTmpl, err = template.ParseFiles("templates/mode.html")
-	return err
+	return nil
 }
 
 // --- Nouveau handler pour choisir le mode ---
 func modeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		mode := r.FormValue("mode")
-		username := r.FormValue("username")
-		username2 := r.FormValue("username2")
-		difficulty := r.FormValue("difficulty")
-		skin := r.FormValue("skin") // Ajout du skin
-		gamemode := r.FormValue("gamemode")
-		ailevel := r.FormValue("ailevel")
-
-		url := "/connect4?username=" + username + "&difficulty=" + difficulty + "&mode=" + mode + "&skin=" + skin + "&gamemode=" + gamemode
-		if username2 != "" {
-			url += "&username2=" + username2
+		r.ParseForm()
+		cfg, err := parseGameConfig(r.PostForm)
+		if cfg.Username == "" {
+			err = errors.Join(err, errors.New("pseudo du joueur 1 obligatoire"))
 		}
-		if ailevel != "" {
-			url += "&ailevel=" + ailevel
+		if err != nil {
+			renderError(w, http.StatusBadRequest, "Paramètres de partie invalides", err, appPath("/"))
+			return
 		}
-
-		http.Redirect(w, r, url, http.StatusSeeOther)
+		if cfg.Mode == "" {
+			cfg.Mode = "normal"
+		}
+		http.Redirect(w, r, appPath("/connect4")+"?"+cfg.values().Encode(), http.StatusSeeOther)
 		return
 	}
-	// On récupère tous les paramètres pour les garder dans le formulaire
-	username := r.URL.Query().Get("username")
-	username2 := r.URL.Query().Get("username2")
-	difficulty := r.URL.Query().Get("difficulty")
-	skin := r.URL.Query().Get("skin") // Ajout du skin
-	gamemode := r.URL.Query().Get("gamemode")
-	ailevel := r.URL.Query().Get("ailevel")
-
-	modeTmpl.Execute(w, map[string]interface{}{
-		"Username":   username,
-		"Username2":  username2,
-		"Difficulty": difficulty,
-		"Skin":       skin, // Ajout du skin
-		"GameMode":   gamemode,
-		"AILevel":    ailevel,
-	})
+	// Les paramètres de l'accueil sont repris dans le formulaire
+	cfg, err := parseGameConfig(r.URL.Query())
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "Paramètres de partie invalides", err, appPath("/"))
+		return
+	}
+	cfg.Mode = ""
+	modeTmpl.Execute(w, struct {
+		Skin   string
+		Values url.Values
+	}{cfg.Skin, cfg.values()})
 }
 
 // --- Modifie startHandler pour rediriger vers /mode ---
 func startHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		username := r.FormValue("username")
-		username2 := r.FormValue("username2")
-		difficulty := r.FormValue("difficulty")
-		skin := r.FormValue("skin")
-		gamemode := r.FormValue("gamemode")
-		ailevel := r.FormValue("ailevel")
-
-		url := "/mode?username=" + username + "&difficulty=" + difficulty + "&skin=" + skin + "&gamemode=" + gamemode
-		if username2 != "" {
-			url += "&username2=" + username2
+		r.ParseForm()
+		cfg, err := parseGameConfig(r.PostForm)
+		if cfg.Username == "" {
+			err = errors.Join(err, errors.New("pseudo du joueur 1 obligatoire"))
 		}
-		if ailevel != "" {
-			url += "&ailevel=" + ailevel
+		if err != nil {
+			renderError(w, http.StatusBadRequest, "Paramètres de partie invalides", err, appPath("/"))
+			return
 		}
-
-		http.Redirect(w, r, url, http.StatusSeeOther)
+		http.Redirect(w, r, appPath("/mode")+"?"+cfg.values().Encode(), http.StatusSeeOther)
 		return
 	}
-	startTmpl.Execute(w, nil)
+	startTmpl.Execute(w, map[string]interface{}{
+		"Profiles": sortedProfiles(),
+		"Engines":  sortedEngines(),
+		"Webhooks": sortedWebhooks(),
+	})
 }
 
-// --- Modifie handler pour prendre en compte le mode ---
-func handler(w http.ResponseWriter, r *http.Request) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	username := r.URL.Query().Get("username")
-	username2 := r.URL.Query().Get("username2")
-	difficulty := r.URL.Query().Get("difficulty")
-	mode := r.URL.Query().Get("mode")
-	skin := r.URL.Query().Get("skin") // Ajout du skin
-	gamemodeStr := r.URL.Query().Get("gamemode")
-	ailevelStr := r.URL.Query().Get("ailevel")
+// slotValue lit l'emplacement joué: le champ "row" en gravité latérale, "col" sinon.
+func slotValue(r *http.Request, g *Game) string {
+	if g != nil && g.Gravity.Horizontal() {
+		return r.FormValue("row")
+	}
+	return r.FormValue("col")
+}
 
-	if mode != "inverse" {
-		mode = "normal"
+// normalizeMode retourne le mode de gravité demandé, "normal" s'il est inconnu.
+func normalizeMode(mode string) string {
+	switch mode {
+	case "inverse", "gauche", "droite", "rotation":
+		return mode
 	}
+	return "normal"
+}
 
-	// Parse GameMode
-	var gameMode GameMode = ModeHumanVsHuman
-	if gamemodeStr == "ai" {
-		gameMode = ModeHumanVsAI
+// boardSize retourne la taille du plateau et le nombre de cases pré-remplies d'une difficulté.
+// Une difficulté inconnue donne le plateau facile.
+func boardSize(difficulty string) (rows, cols, prefill int) {
+	p, ok := boardPresets[difficulty]
+	if !ok {
+		p = boardPresets["easy"]
 	}
+	return p.Rows, p.Cols, p.Prefill
+}
 
-	// Parse AILevel
-	var aiLevel AILevel = AIEasy
-	switch ailevelStr {
+// parseAILevel retourne le niveau d'IA demandé ("easy", "medium" ou "hard"); facile par défaut.
+func parseAILevel(s string) AILevel {
+	switch s {
 	case "medium":
-		aiLevel = AIMedium
+		return AIMedium
 	case "hard":
-		aiLevel = AIHard
-	default:
-		aiLevel = AIEasy
+		return AIHard
 	}
+	return AIEasy
+}
 
-	rows, cols, prefill := 6, 7, 0
-	switch difficulty {
-	case "easy":
-		rows, cols, prefill = 6, 7, 0
-	case "normal":
-		rows, cols, prefill = 7, 8, 0
-	case "hard":
-		rows, cols, prefill = 8, 10, 7
+// --- Modifie handler pour prendre en compte le mode ---
+func handler(w http.ResponseWriter, r *http.Request) {
+	player := currentPlayer(w, r)
+	sess := player.solo()
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	game := sess.Game
+
+	cfg, err := parseGameConfig(r.URL.Query())
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "Paramètres de partie invalides", err, appPath("/"))
+		return
 	}
+	username, username2, difficulty, skin := cfg.Username, cfg.Username2, cfg.Difficulty, cfg.Skin
+	mode := normalizeMode(cfg.Mode)
+	gameMode, ailevelStr := cfg.GameMode, cfg.AILevel
+	timeControl := parseTimeControl(cfg.Cadence)
+	bestOf := cfg.Serie
+
+	aiLevel := parseAILevel(ailevelStr)
+	profile := lookupProfile(ailevelStr, aiLevel)
+	opponent := externalBot(ailevelStr)
+
+	rows, cols, prefill := boardSize(difficulty)
 
 	// Normalise username2 pour le mode IA afin d'éviter une réinitialisation en boucle
 	normUsername2 := username2
 	if gameMode == ModeHumanVsAI && normUsername2 == "" {
 		normUsername2 = "IA"
 	}
+	if username != "" {
+		mutex.Lock()
+		player.Name, player.Skin = username, skin
+		mutex.Unlock()
+	}
+
+	// startGame lance une partie, dans la série en cours si elle n'est pas terminée. Si l'IA commence,
+	// son coup prend une place de recherche; faute de place, la réponse 429 est écrite et ok est faux.
+	startGame := func(match *Match) (g *Game, ok bool) {
+		if bestOf > 1 && (match == nil || match.Over()) {
+			match = NewMatch(bestOf)
+		} else if bestOf <= 1 {
+			match = nil
+		}
+		g = NewGame(rows, cols, prefill, difficulty, username, normUsername2, mode, skin, gameMode, aiLevel)
+		g.SetTimeControl(timeControl)
+		g.Profile = profile
+		g.Opponent = opponent
+		if match != nil {
+			g.Match = match
+			g.CurrentPlayer = match.Starter()
+		}
+		if g.aiToMove() {
+			release, ok := sess.acquireSearch(w, r)
+			if !ok {
+				return nil, false
+			}
+			defer release()
+			g.playAIMoveIfNeeded()
+		}
+		return g, true
+	}
 
-	if game == nil || (username != "" && (game.Username != username || game.Username2 != normUsername2 || game.Difficulty != difficulty || game.Mode != mode || game.GameMode != gameMode || game.AILevel != aiLevel || game.Skin != skin)) {
-		game = NewGame(rows, cols, prefill, difficulty, username, normUsername2, mode, skin, gameMode, aiLevel)
+	// Une partie en cours est remplacée dès qu'un paramètre de l'adresse diffère
+	var changed []string
+	if game != nil && username != "" {
+		for _, c := range []struct {
+			name string
+			diff bool
+		}{
+			{"username", game.Username != username},
+			{"username2", game.Username2 != normUsername2},
+			{"difficulty", game.Difficulty != difficulty},
+			{"mode", game.Mode != mode},
+			{"gamemode", game.GameMode != gameMode},
+			{"ailevel", game.AILevel != aiLevel},
+			{"profile", game.aiProfile() != profile},
+			{"opponent", game.Opponent != opponent},
+			{"skin", game.Skin != skin},
+			{"cadence", game.TimeControl.Name != timeControl.Name},
+			{"serie", game.matchBestOf() != max(bestOf, 1)},
+		} {
+			if c.diff {
+				changed = append(changed, c.name)
+			}
+		}
 	}
+	if game == nil || len(changed) > 0 {
+		if len(changed) > 0 {
+			logger(r.Context()).Debug("paramètres modifiés, nouvelle partie", "game", sess.ID, "changed", changed)
+		}
+		g, ok := startGame(nil)
+		if !ok {
+			return
+		}
+		game = g
+		sess.Game = game
+		sess.touch(r.Context())
+	}
+	game.checkTimeout()
 
 	if r.Method == "POST" {
 		r.ParseForm()
+		if !checkCSRF(r, player) {
+			rejectCSRF(w, r)
+			return
+		}
 		if r.FormValue("reset") == "1" {
-			game = nil
-			http.Redirect(w, r, "/", http.StatusSeeOther)
+			sess.Game = nil
+			sess.touch(r.Context())
+			http.Redirect(w, r, appPath("/"), http.StatusSeeOther)
 			return
 		}
 		if r.FormValue("rematch") == "1" {
-			game = NewGame(rows, cols, prefill, difficulty, username, normUsername2, mode, skin, gameMode, aiLevel)
-		} else if colStr := r.FormValue("col"); colStr != "" {
+			g, ok := startGame(game.Match)
+			if !ok {
+				return
+			}
+			// La partie a pu changer pendant l'attente d'une place de recherche
+			if cur := sess.Game; cur != nil {
+				g.Chat, g.ChatSeq = cur.Chat, cur.ChatSeq
+			}
+			game = g
+			sess.Game = game
+		} else if colStr := slotValue(r, game); colStr != "" {
 			col, err := strconv.Atoi(colStr)
-			if err == nil && game != nil && !game.GameOver {
-				if game.GameMode == ModeHumanVsAI {
-					if game.CurrentPlayer == 1 && game.DropToken(col) {
-						game.playAIMoveIfNeeded()
-					}
-				} else {
-					game.DropToken(col)
+			switch {
+			case err != nil:
+			case !game.moveAllowed(r):
+				logger(r.Context()).Warn("coup refusé: la place du formulaire n'a pas le trait", "game", sess.ID, "current", game.CurrentPlayer)
+			case game.GameMode == ModeHumanVsAI:
+				// La place de recherche est réservée avant le coup: l'IA répond toujours
+				release, ok := sess.acquireSearch(w, r)
+				if !ok {
+					return
+				}
+				if sess.Game == game && game.CurrentPlayer == 1 && game.DropToken(col) {
+					game.playAIMoveIfNeeded()
 				}
+				release()
+			default:
+				game.DropToken(col)
 			}
 		}
+		sess.touch(r.Context())
 	}
 
-	// Prépare le message de fin si besoin
-	endMessage := ""
-	if game.GameOver {
-		if game.Winner == 1 {
-			endMessage = "🎉 Victoire !"
-		} else if game.Winner == 2 {
-			if game.GameMode == ModeHumanVsAI {
-				endMessage = "🤖 L'IA a gagné !"
-			} else {
-				endMessage = "💀 Défaite !"
-			}
-		} else {
-			endMessage = "Match nul !"
-		}
-	}
-
-	data := struct {
-		BoardHTML     template.HTML
-		CurrentPlayer int
-		Winner        int
-		GameOver      bool
-		Gravity       Gravity
-		Username      string
-		Username1     string
-		Username2     string
-		Difficulty    string
-		Rows          int
-		Cols          int
-		Mode          string
-		GameMode      GameMode
-		AILevel       AILevel
-		Skin          string
-		EndMessage    string
-	}{
-		BoardHTML:     renderBoard(game),
-		CurrentPlayer: game.CurrentPlayer,
-		Winner:        game.Winner,
-		GameOver:      game.GameOver,
-		Gravity:       game.Gravity,
-		Username:      game.Username,
-		Username1:     game.Username1,
-		Username2:     game.Username2,
-		Difficulty:    game.Difficulty,
-		Rows:          game.Rows,
-		Cols:          game.Cols,
-		Mode:          game.Mode,
-		GameMode:      game.GameMode,
-		AILevel:       game.AILevel,
-		Skin:          game.Skin,
-		EndMessage:    endMessage,
-	}
+	data := newGameView(game, 1)
+	data.BoardHTML = renderBoard(game, soloView(game, player))
+	data.AnalyseURL = appPath("/analyse")
+	sess.describe(&data, player)
+	data.WatchURL = absoluteURL(r, "/watch?id="+sess.ID)
 	pageTmpl.Execute(w, data)
 }
 
+// endMessage retourne le message de fin de partie vu depuis la place seat (1 ou 2; 0 pour un spectateur).
+func endMessage(g *Game, seat int) string {
+	if !g.GameOver {
+		return ""
+	}
+	names := [3]string{"", g.Username1, g.Username2}
+	msg := "Match nul !"
+	switch {
+	case g.Winner == 0:
+	case g.Winner == seat:
+		msg = "🎉 Victoire !"
+	case g.Winner == 2 && g.GameMode == ModeHumanVsAI:
+		msg = "🤖 L'IA a gagné !"
+	case seat == 0:
+		msg = "🏆 " + names[g.Winner] + " a gagné !"
+	default:
+		msg = "💀 Défaite !"
+	}
+	if g.TimedOut {
+		msg = "⏱ Temps écoulé - " + msg
+	}
+	if g.Forfeited && g.Winner != 0 {
+		msg = "🏳 Forfait de " + names[3-g.Winner] + " - " + msg
+	}
+	if g.Match != nil && g.Match.Over() {
+		switch g.Match.Winner() {
+		case 1, 2:
+			msg += " " + names[g.Match.Winner()] + " remporte la série !"
+		default:
+			msg += " Série à égalité !"
+		}
+	}
+	return msg
+}
+
+// gameView regroupe les données de la page de jeu.
+type gameView struct {
+	BoardHTML      template.HTML
+	CurrentPlayer  int
+	Winner         int
+	GameOver       bool
+	Gravity        Gravity
+	Username       string
+	Username1      string
+	Username2      string
+	Difficulty     string
+	Rows           int
+	Cols           int
+	Mode           string
+	GameMode       GameMode
+	AILevel        AILevel
+	Skin           string
+	EndMessage     string
+	Timed          bool
+	Correspondence bool
+	Clock1Ms       int64
+	Clock2Ms       int64
+	Match          *Match
+	MatchOver      bool
+	RematchLabel   string
+	AIProfile      *AIProfile
+	Opponent       Bot
+	AnalyseURL     string
+
+	// Suivi en direct et salles en ligne
+	SessionID  string
+	Version    int
+	Spectators int
+	Spectator  bool // page en lecture seule
+	WatchURL   string
+	RoomID     string
+	Seat       int // place du visiteur (0 s'il n'est pas assis)
+	Rating1    int
+	Rating2    int
+	Waiting    bool // en attente d'un adversaire
+	CanJoin    bool
+	ShareURL   string
+	CSRF       string // jeton anti-CSRF des formulaires de la page
+
+	// Discussion
+	ChatEnabled bool
+	ChatChannel int
+	Chat        []ChatMessage
+	ChatLast    int // numéro du dernier message affiché
+	Reactions   []Reaction
+	ChatMax     int
+}
+
+// newGameView prépare les données de la page pour la partie vue depuis la place seat.
+func newGameView(game *Game, seat int) gameView {
+	return gameView{
+		CurrentPlayer:  game.CurrentPlayer,
+		Winner:         game.Winner,
+		GameOver:       game.GameOver,
+		Gravity:        game.Gravity,
+		Username:       game.Username,
+		Username1:      game.Username1,
+		Username2:      game.Username2,
+		Difficulty:     game.Difficulty,
+		Rows:           game.Rows,
+		Cols:           game.Cols,
+		Mode:           game.Mode,
+		GameMode:       game.GameMode,
+		AILevel:        game.AILevel,
+		Skin:           game.Skin,
+		EndMessage:     endMessage(game, seat),
+		Timed:          game.TimeControl.Enabled(),
+		Correspondence: game.TimeControl.Correspondence,
+		Clock1Ms:       game.TimeLeft(1).Milliseconds(),
+		Clock2Ms:       game.TimeLeft(2).Milliseconds(),
+		Match:          game.Match,
+		MatchOver:      game.Match != nil && game.Match.Over(),
+		RematchLabel:   rematchLabel(game),
+		AIProfile:      game.aiProfile(),
+		Opponent:       game.Opponent,
+		Seat:           seat,
+	}
+}
+
+// commands sont les sous-commandes du programme; sans sous-commande, le serveur web est lancé.
+var commands = []struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}{
+	{"serve", "serveur web (options: power4 serve -h)", runServe},
+	{"play", "partie dans le terminal, en local ou sur un serveur", withConfig(runPlay)},
+	{"analyse", "analyse d'une partie enregistrée ou d'une suite de coups", withConfig(runAnalyse)},
+	{"bench", "mesure de la vitesse de recherche de l'IA", withConfig(runBench)},
+	{"selfplay", "réglage des poids d'évaluation par auto-jeu", runSelfPlay},
+	{"book", "génération de la bibliothèque d'ouvertures", runBookGenerator},
+	{"arena", "tournoi entre profils d'IA et moteurs", withConfig(runArena)},
+	{"stubbot", "bot HTTP de test", runStubBot},
+	{"healthcheck", "sonde /readyz du serveur local, pour les conteneurs", runHealthcheck},
+}
+
+// withConfig charge les fichiers de configuration du répertoire des données avant de lancer la commande.
+func withConfig(run func(args []string) error) func(args []string) error {
+	return func(args []string) error {
+		if err := loadConfigFiles(); err != nil {
+			return err
+		}
+		defer closeEngines()
+		return run(args)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: power4 [commande] [options]")
+	fmt.Fprintln(os.Stderr, "Commandes:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(os.Stderr, "Sans commande, power4 lance le serveur web (serve).")
+}
+
 func main() {
-	if err := loadTemplates(); err != nil {
-		panic("Erreur chargement templates: " + err.Error())
-	}
-	http.HandleFunc("/", startHandler)
-	http.HandleFunc("/mode", modeHandler)
-	http.HandleFunc("/ai-move", aiMoveHandler)
-	http.HandleFunc("/connect4", handler)
-	// Servez le CSS avec des en-têtes no-cache pour éviter les problèmes de cache navigateur
-	http.HandleFunc("/style.css", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		http.ServeFile(w, r, "style.css")
-	})
-	http.Handle("/favicon.svg", http.FileServer(http.Dir(".")))
-	fmt.Println("Serveur Puissance 4 Go sur http://localhost:8081/")
-	http.ListenAndServe(":8081", nil)
+	dataDir = envOr("P4_DATA_DIR", dataDir)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "Erreur %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Commande inconnue: %q\n", name)
+	usage()
+	os.Exit(2)
 }
 
 // aiMoveHandler effectue le coup de l'IA lorsqu'il est appelé (endpoint POST)
@@ -871,17 +1272,38 @@ func aiMoveHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		return
 	}
+	if !checkCSRF(r, currentPlayer(w, r)) {
+		http.Error(w, errNoCSRF.Error(), http.StatusForbidden)
+		return
+	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
+	sess := requestSession(w, r)
+	if sess == nil {
+		http.NotFound(w, r)
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
 
-	if game == nil || game.GameMode != ModeHumanVsAI || game.GameOver || game.CurrentPlayer != 2 {
+	if !sess.Game.aiToMove() {
 		// Rien à faire
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	release, ok := sess.acquireSearch(w, r)
+	if !ok {
+		return
+	}
+	defer release()
 
+	// La partie a pu changer pendant l'attente d'une place
+	game := sess.Game
+	if !game.aiToMove() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	game.playAIMoveIfNeeded()
+	sess.touch(r.Context())
 
 	// OK
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,64 @@
+package main
+
+// Match regroupe plusieurs parties d'une série "au meilleur des N".
+// Le joueur qui commence alterne d'une partie à l'autre.
+type Match struct {
+	BestOf int
+	Wins   [2]int // victoires des joueurs 1 et 2
+	Draws  int
+	Played int
+}
+
+// NewMatch crée une série au meilleur des bestOf parties (ramené à un nombre impair).
+func NewMatch(bestOf int) *Match {
+	if bestOf < 1 {
+		bestOf = 1
+	}
+	if bestOf%2 == 0 {
+		bestOf++
+	}
+	return &Match{BestOf: bestOf}
+}
+
+// Starter retourne le joueur qui commence la prochaine partie.
+func (m *Match) Starter() int {
+	if m.Played%2 == 0 {
+		return 1
+	}
+	return 2
+}
+
+// Record comptabilise le résultat d'une partie terminée.
+func (m *Match) Record(winner int) {
+	m.Played++
+	switch winner {
+	case 1, 2:
+		m.Wins[winner-1]++
+	default:
+		m.Draws++
+	}
+}
+
+// Winner retourne le vainqueur de la série (0 si elle n'est pas décidée ou se termine à égalité).
+func (m *Match) Winner() int {
+	need := m.BestOf/2 + 1
+	for p := 1; p <= 2; p++ {
+		if m.Wins[p-1] >= need {
+			return p
+		}
+	}
+	if m.Played >= m.BestOf {
+		switch {
+		case m.Wins[0] > m.Wins[1]:
+			return 1
+		case m.Wins[1] > m.Wins[0]:
+			return 2
+		}
+	}
+	return 0
+}
+
+// Over indique si la série est terminée.
+func (m *Match) Over() bool {
+	return m.Winner() != 0 || m.Played >= m.BestOf
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNewMatchOddLength(t *testing.T) {
+	for _, tt := range []struct{ in, want int }{{0, 1}, {1, 1}, {2, 3}, {3, 3}, {6, 7}} {
+		if got := NewMatch(tt.in).BestOf; got != tt.want {
+			t.Errorf("NewMatch(%d).BestOf = %d, attendu %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchStarterAlternates(t *testing.T) {
+	m := NewMatch(5)
+	for i, want := range []int{1, 2, 1, 2, 1} {
+		if got := m.Starter(); got != want {
+			t.Fatalf("partie %d: le joueur %d commence, attendu %d", i+1, got, want)
+		}
+		m.Record(0)
+	}
+}
+
+func TestMatchRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		bestOf  int
+		results []int // vainqueur de chaque partie (0: nul)
+		winner  int
+		over    bool
+	}{
+		{"série en cours", 3, []int{1}, 0, false},
+		{"victoire avant la fin", 3, []int{2, 2}, 2, true},
+		{"victoire à la dernière partie", 3, []int{1, 2, 1}, 1, true},
+		{"nuls puis avantage", 3, []int{0, 0, 2}, 2, true},
+		{"égalité finale", 3, []int{1, 0, 2}, 0, true},
+		{"partie simple nulle", 1, []int{0}, 0, true},
+		{"majorité atteinte malgré les nuls", 5, []int{0, 1, 1, 1}, 1, true},
+	}
+	for _, tt := range tests {
+		m := NewMatch(tt.bestOf)
+		wins, draws := [2]int{}, 0
+		for _, w := range tt.results {
+			m.Record(w)
+			if w == 0 {
+				draws++
+			} else {
+				wins[w-1]++
+			}
+		}
+		if m.Played != len(tt.results) || m.Wins != wins || m.Draws != draws {
+			t.Errorf("%s: %d parties, victoires %v, %d nuls", tt.name, m.Played, m.Wins, m.Draws)
+		}
+		if m.Winner() != tt.winner || m.Over() != tt.over {
+			t.Errorf("%s: Winner = %d, Over = %v; attendu %d, %v", tt.name, m.Winner(), m.Over(), tt.winner, tt.over)
+		}
+	}
+}
+
+func TestFinishRecordsMatchOnce(t *testing.T) {
+	g := newTestGame("normal")
+	g.Match = NewMatch(3)
+	g.finish(2)
+	g.finish(1)
+	if g.Match.Played != 1 || g.Match.Wins != [2]int{0, 1} {
+		t.Errorf("série après la partie: %+v", g.Match)
+	}
+}
+
+func TestRoomSeriesLengths(t *testing.T) {
+	// Seules les longueurs impaires proposées sont acceptées, comme pour /connect4
+	tests := []struct {
+		serie string
+		want  int
+	}{
+		{"1", 1}, {"3", 3}, {"5", 5}, {"7", 7},
+		{"2", 1}, {"4", 1}, {"9", 1}, {"-1", 1}, {"", 1},
+	}
+	for _, tt := range tests {
+		if got := parseRoomSettings(postForm(url.Values{"serie": {tt.serie}})).BestOf; got != tt.want {
+			t.Errorf("serie=%q: %d parties, attendu %d", tt.serie, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limites des histogrammes, en secondes.
+var (
+	httpBuckets   = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	aiMoveBuckets = []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+)
+
+// counterVec est une famille de compteurs distingués par les valeurs de leurs étiquettes.
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64 // clé: valeurs des étiquettes séparées par \x00
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+// inc incrémente le compteur des valeurs d'étiquettes indiquées (dans l'ordre de labels).
+func (c *counterVec) inc(values ...string) {
+	c.mu.Lock()
+	c.values[strings.Join(values, "\x00")]++
+	c.mu.Unlock()
+}
+
+// labelValues retourne les valeurs distinctes, triées, de l'étiquette d'indice i.
+func (c *counterVec) labelValues(i int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := map[string]bool{}
+	var values []string
+	for key := range c.values {
+		if v := strings.Split(key, "\x00")[i]; !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// get retourne la valeur du compteur.
+func (c *counterVec) get(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(values, "\x00")]
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelString(c.labels, key, ""), formatValue(c.values[key]))
+	}
+}
+
+// histogramVec est une famille d'histogrammes distingués par les valeurs de leurs étiquettes.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogram
+}
+
+// histogram compte les observations par limite (non cumulées), leur somme et leur nombre.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogram{}}
+}
+
+// observe enregistre une durée.
+func (h *histogramVec) observe(d time.Duration, values ...string) {
+	v := d.Seconds()
+	key := strings.Join(values, "\x00")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, key, formatValue(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, key, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, key, ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, key, ""), s.count)
+	}
+}
+
+// labelEscaper échappe une valeur d'étiquette selon le format texte de Prometheus: seuls la barre
+// oblique inverse, le guillemet et le saut de ligne le sont; le reste (accents compris) est écrit tel quel en UTF-8.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelString formate les étiquettes d'une série, suivies de l'étiquette le des histogrammes si elle est indiquée.
+func labelString(names []string, key, le string) string {
+	var parts []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, "\x00") {
+			parts = append(parts, names[i]+`="`+labelEscaper.Replace(v)+`"`)
+		}
+	}
+	if le != "" {
+		parts = append(parts, `le="`+le+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	gamesStarted = newCounterVec("power4_games_started_total",
+		"Parties lancées, par mode de gravité, difficulté et type d'adversaire.", "mode", "difficulty", "opponent")
+	gamesFinished = newCounterVec("power4_games_finished_total",
+		"Parties terminées, par mode de gravité, difficulté et type d'adversaire.", "mode", "difficulty", "opponent")
+	aiGames = newCounterVec("power4_ai_games_finished_total",
+		"Parties contre l'IA terminées, par IA (profil, moteur ou bot) et résultat (ai, human ou draw).", "level", "result")
+	aiMoveSeconds = newHistogramVec("power4_ai_move_duration_seconds",
+		"Temps de calcul des coups de l'IA, par IA (profil, moteur ou bot).", aiMoveBuckets, "level")
+	httpRequests = newCounterVec("power4_http_requests_total",
+		"Requêtes HTTP, par page et code de statut.", "handler", "code")
+	httpDuration = newHistogramVec("power4_http_request_duration_seconds",
+		"Durée des requêtes HTTP, par page.", httpBuckets, "handler")
+	rateLimited = newCounterVec("power4_rate_limited_total",
+		"Requêtes refusées (429), par limite: ip, session ou search.", "limit")
+
+	// searchNanos cumule le temps passé dans les recherches (voir searchNodes).
+	searchNanos atomic.Int64
+	startTime   = time.Now()
+)
+
+// String retourne le nom du niveau, tel qu'il est saisi dans les formulaires.
+func (l AILevel) String() string {
+	switch l {
+	case AIMedium:
+		return "medium"
+	case AIHard:
+		return "hard"
+	}
+	return "easy"
+}
+
+// opponentKind retourne le type d'adversaire d'une partie: "ai" ou "human".
+func opponentKind(g *Game) string {
+	if g.GameMode == ModeHumanVsAI {
+		return "ai"
+	}
+	return "human"
+}
+
+// aiName retourne le nom de l'IA adverse dans les métriques: moteur ou bot externe, sinon profil
+// (easy, medium, hard ou profil personnalisé).
+func (g *Game) aiName() string {
+	if g.Opponent != nil {
+		return g.Opponent.Name()
+	}
+	return g.aiProfile().Name
+}
+
+// countGame compte le début et la fin de la partie en cours, une seule fois chacun. Appelé avec s.mu verrouillé.
+func (s *GameSession) countGame() {
+	g := s.Game
+	if g == nil {
+		return
+	}
+	if s.countedStart != g {
+		s.countedStart = g
+		gamesStarted.inc(g.Mode, g.Difficulty, opponentKind(g))
+	}
+	if g.GameOver && s.countedOver != g {
+		s.countedOver = g
+		gamesFinished.inc(g.Mode, g.Difficulty, opponentKind(g))
+		if g.GameMode == ModeHumanVsAI {
+			result := [3]string{"draw", "human", "ai"}[g.Winner]
+			aiGames.inc(g.aiName(), result)
+		}
+	}
+}
+
+// statusRecorder retient le code de statut de la réponse; il reste un http.Flusher pour les flux d'événements.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if sr.status == 0 {
+		sr.status = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+func (sr *statusRecorder) Flush() {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// instrument compte les requêtes de la page name, mesure leur durée et les journalise
+// avec un identifiant de requête, renvoyé dans l'en-tête X-Request-ID.
+func instrument(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, id := withRequestID(r)
+		w.Header().Set(requestIDHeader, id)
+		sr := &statusRecorder{ResponseWriter: w}
+		h(sr, r)
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+		httpRequests.inc(name, strconv.Itoa(sr.status))
+		httpDuration.observe(elapsed, name)
+		logger(r.Context()).Info("requête", "method", r.Method, "path", r.URL.Path, "handler", name,
+			"status", sr.status, "duration", elapsed, "remote", r.RemoteAddr)
+	}
+}
+
+// metricsHandler expose les métriques du serveur au format texte de Prometheus.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	mutex.Lock()
+	sessionCount, roomCount, playerCount := len(sessions), 0, len(players)
+	for _, s := range sessions {
+		if s.Room != nil {
+			roomCount++
+		}
+	}
+	mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	gauge := func(name, help string, v float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatValue(v))
+	}
+	gauge("power4_sessions_active", "Parties en mémoire (locales et en salle).", float64(sessionCount))
+	gauge("power4_rooms_active", "Salles en mémoire.", float64(roomCount))
+	gauge("power4_players", "Visiteurs connus.", float64(playerCount))
+	gauge("power4_uptime_seconds", "Temps écoulé depuis le démarrage du serveur.", time.Since(startTime).Seconds())
+
+	gamesStarted.write(bw)
+	gamesFinished.write(bw)
+	aiGames.write(bw)
+	fmt.Fprintf(bw, "# HELP power4_ai_win_rate Part des parties contre l'IA gagnées par l'IA, par IA.\n# TYPE power4_ai_win_rate gauge\n")
+	for _, level := range aiGames.labelValues(0) {
+		ai, human, draw := aiGames.get(level, "ai"), aiGames.get(level, "human"), aiGames.get(level, "draw")
+		if total := ai + human + draw; total > 0 {
+			fmt.Fprintf(bw, "power4_ai_win_rate{level=%q} %s\n", level, formatValue(ai/total))
+		}
+	}
+	aiMoveSeconds.write(bw)
+
+	nodes, searched := searchNodes.Load(), time.Duration(searchNanos.Load())
+	fmt.Fprintf(bw, "# HELP power4_search_nodes_total Positions visitées par les recherches.\n# TYPE power4_search_nodes_total counter\npower4_search_nodes_total %d\n", nodes)
+	fmt.Fprintf(bw, "# HELP power4_search_seconds_total Temps passé dans les recherches.\n# TYPE power4_search_seconds_total counter\npower4_search_seconds_total %s\n", formatValue(searched.Seconds()))
+	rate := 0.0
+	if searched > 0 {
+		rate = float64(nodes) / searched.Seconds()
+	}
+	gauge("power4_search_nodes_per_second", "Vitesse moyenne des recherches depuis le démarrage.", rate)
+	gauge("power4_ai_searches_active", "Recherches de l'IA en cours.", float64(aiSearches.active()))
+	gauge("power4_ai_searches_queued", "Recherches de l'IA en attente d'une place.", float64(aiSearches.queued()))
+
+	httpRequests.write(bw)
+	httpDuration.write(bw)
+	rateLimited.write(bw)
+}
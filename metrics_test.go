@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterVecWrite(t *testing.T) {
+	c := newCounterVec("test_total", "Compteur de test.", "mode", "code")
+	c.inc("normal", "200")
+	c.inc("normal", "200")
+	c.inc(`gau"che`, "404")
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	c.write(w)
+	w.Flush()
+	want := `# HELP test_total Compteur de test.
+# TYPE test_total counter
+test_total{mode="gau\"che",code="404"} 1
+test_total{mode="normal",code="200"} 2
+`
+	if b.String() != want {
+		t.Errorf("sortie:\n%s\nattendu:\n%s", b.String(), want)
+	}
+	if got := c.labelValues(0); len(got) != 2 || got[0] != `gau"che` || got[1] != "normal" {
+		t.Errorf("labelValues(0) = %q", got)
+	}
+}
+
+func TestLabelString(t *testing.T) {
+	tests := []struct{ value, want string }{
+		{"Zoé", `{bot="Zoé"}`},
+		{"a\tb", "{bot=\"a\tb\"}"},
+		{`c:\bots`, `{bot="c:\\bots"}`},
+		{"deux\nlignes", `{bot="deux\nlignes"}`},
+		{`"cité"`, `{bot="\"cité\""}`},
+	}
+	for _, tt := range tests {
+		if got := labelString([]string{"bot"}, tt.value, ""); got != tt.want {
+			t.Errorf("labelString(%q) = %s, attendu %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramVecWrite(t *testing.T) {
+	h := newHistogramVec("test_seconds", "Durées de test.", []float64{0.01, 0.1, 1}, "page")
+	for _, d := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, 3 * time.Second} {
+		h.observe(d, "jeu")
+	}
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	h.write(w)
+	w.Flush()
+	want := `# HELP test_seconds Durées de test.
+# TYPE test_seconds histogram
+test_seconds_bucket{page="jeu",le="0.01"} 2
+test_seconds_bucket{page="jeu",le="0.1"} 3
+test_seconds_bucket{page="jeu",le="1"} 3
+test_seconds_bucket{page="jeu",le="+Inf"} 4
+test_seconds_sum{page="jeu"} 3.065
+test_seconds_count{page="jeu"} 4
+`
+	if b.String() != want {
+		t.Errorf("sortie:\n%s\nattendu:\n%s", b.String(), want)
+	}
+}
+
+func TestCountGameOnce(t *testing.T) {
+	g := NewGame(6, 7, 0, "easy", "Alice", "IA", "gauche", "classic", ModeHumanVsAI, AIMedium)
+	sess := &GameSession{Game: g}
+	started := gamesStarted.get("gauche", "easy", "ai")
+	finished := gamesFinished.get("gauche", "easy", "ai")
+	won := aiGames.get("medium", "ai")
+
+	sess.countGame()
+	sess.countGame()
+	if got := gamesStarted.get("gauche", "easy", "ai") - started; got != 1 {
+		t.Errorf("partie comptée %g fois au début", got)
+	}
+	g.finish(2)
+	sess.countGame()
+	sess.countGame()
+	if got := gamesFinished.get("gauche", "easy", "ai") - finished; got != 1 {
+		t.Errorf("partie comptée %g fois à la fin", got)
+	}
+	if got := aiGames.get("medium", "ai") - won; got != 1 {
+		t.Errorf("victoire de l'IA comptée %g fois", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	h := instrument("essai-metriques", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/essai", nil))
+	aiGames.inc("profil-metriques", "ai")
+	aiGames.inc("profil-metriques", "human")
+	aiGames.inc("profil-metriques", "draw")
+	aiGames.inc("profil-metriques", "ai")
+
+	w := httptest.NewRecorder()
+	metricsHandler(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`power4_http_requests_total{handler="essai-metriques",code="418"} 1`,
+		`power4_http_request_duration_seconds_count{handler="essai-metriques"} 1`,
+		`power4_ai_win_rate{level="profil-metriques"} 0.5`,
+		"# TYPE power4_sessions_active gauge",
+		"# TYPE power4_search_nodes_total counter",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("ligne absente: %s", line)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Couleurs ANSI des jetons du client terminal.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBold   = "\x1b[1m"
+	ansiInvert = "\x1b[7m"
+	ansiDim    = "\x1b[2m"
+)
+
+// playSettings sont les paramètres d'une partie lancée depuis le terminal (mêmes valeurs que le formulaire web).
+type playSettings struct {
+	Difficulty string
+	Mode       string
+	AI         string // niveau, profil ou bot adverse; vide pour deux joueurs sur le même terminal
+	Name1      string
+	Name2      string
+	Cadence    string
+}
+
+// playBackend joue la partie: localement avec le moteur du programme, ou à distance via l'API d'un serveur.
+type playBackend interface {
+	New() (GameState, error)
+	Play(slot int) (GameState, error)
+}
+
+// localBackend fait tourner la partie dans le processus.
+type localBackend struct {
+	settings playSettings
+	game     *Game
+}
+
+func (lb *localBackend) New() (GameState, error) {
+	s := lb.settings
+	gameMode := ModeHumanVsHuman
+	if s.AI != "" {
+		gameMode = ModeHumanVsAI
+	}
+	rows, cols, prefill := boardSize(s.Difficulty)
+	g := NewGame(rows, cols, prefill, s.Difficulty, s.Name1, s.Name2, normalizeMode(s.Mode), "", gameMode, parseAILevel(s.AI))
+	g.SetTimeControl(parseTimeControl(s.Cadence))
+	g.Profile = lookupProfile(s.AI, g.AILevel)
+	g.Opponent = externalBot(s.AI)
+	lb.game = g
+	return gameState(g, localSeat(g)), nil
+}
+
+func (lb *localBackend) Play(slot int) (GameState, error) {
+	g := lb.game
+	if g.checkTimeout() || g.GameOver {
+		return gameState(g, localSeat(g)), nil
+	}
+	if !g.DropToken(slot) {
+		return gameState(g, localSeat(g)), errors.New("coup illégal")
+	}
+	g.playAIMoveIfNeeded()
+	return gameState(g, localSeat(g)), nil
+}
+
+// remoteBackend joue la partie locale d'un visiteur d'un serveur distant, via /api/game.
+type remoteBackend struct {
+	settings playSettings
+	base     string
+	client   *http.Client
+}
+
+func newRemoteBackend(base string, settings playSettings) (*remoteBackend, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("adresse du serveur invalide: %q", base)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteBackend{
+		settings: settings,
+		base:     strings.TrimSuffix(base, "/"),
+		client:   &http.Client{Jar: jar, Timeout: time.Minute},
+	}, nil
+}
+
+// post envoie le formulaire à l'API et décode l'état de la partie.
+func (rb *remoteBackend) post(form url.Values) (GameState, error) {
+	var st GameState
+	resp, err := rb.client.PostForm(rb.base+"/api/game", form)
+	if err != nil {
+		return st, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return st, fmt.Errorf("serveur: %s", strings.TrimSpace(string(msg)))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&st)
+	return st, err
+}
+
+func (rb *remoteBackend) New() (GameState, error) {
+	s := rb.settings
+	form := url.Values{
+		"action":     {"new"},
+		"difficulty": {s.Difficulty},
+		"mode":       {s.Mode},
+		"username":   {s.Name1},
+		"username2":  {s.Name2},
+		"cadence":    {s.Cadence},
+	}
+	if s.AI != "" {
+		form.Set("gamemode", "ai")
+		form.Set("ailevel", s.AI)
+	}
+	return rb.post(form)
+}
+
+func (rb *remoteBackend) Play(slot int) (GameState, error) {
+	return rb.post(url.Values{"slot": {strconv.Itoa(slot)}})
+}
+
+// useColor décide de l'affichage en couleurs: "always", "never" ou "auto" (terminal, sans NO_COLOR).
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// tokenText retourne le jeton d'un joueur sur trois caractères: X et O en ASCII, pastilles colorées en ANSI.
+// Le dernier coup est entre crochets (en gras), les jetons gagnants entre parenthèses (en vidéo inverse).
+func tokenText(player int, last, winning, color bool) string {
+	if player == 0 {
+		if color {
+			return ansiDim + " · " + ansiReset
+		}
+		return " . "
+	}
+	if !color {
+		mark := [3]string{"", "X", "O"}[player]
+		switch {
+		case winning:
+			return "(" + mark + ")"
+		case last:
+			return "[" + mark + "]"
+		}
+		return " " + mark + " "
+	}
+	style := [3]string{"", ansiRed, ansiYellow}[player]
+	if winning {
+		style += ansiInvert
+	} else if last {
+		style += ansiBold
+	}
+	mark := " ● "
+	if last && !winning {
+		mark = "[●]"
+	}
+	return style + mark + ansiReset
+}
+
+// drawBoard affiche le plateau: numéros des colonnes en haut, ou des lignes à gauche en gravité latérale.
+func drawBoard(w io.Writer, st GameState, color bool) {
+	horizontal := st.Gravity == "left" || st.Gravity == "right"
+	winning := map[[2]int]bool{}
+	for _, p := range st.Winning {
+		winning[p] = true
+	}
+	arrow := map[string]string{"down": "↓", "up": "↑", "left": "←", "right": "→"}[st.Gravity]
+	fmt.Fprintf(w, "\n%s %s  contre %s %s   (gravité %s %s)\n",
+		tokenText(1, false, false, color), st.Players[0], tokenText(2, false, false, color), st.Players[1], st.Mode, arrow)
+
+	if !horizontal {
+		fmt.Fprint(w, "      ")
+		for c := 0; c < st.Cols; c++ {
+			fmt.Fprintf(w, "%-3d", c+1)
+		}
+		fmt.Fprintln(w)
+	}
+	for r := 0; r < st.Rows; r++ {
+		if horizontal {
+			fmt.Fprintf(w, "%3d |", r+1)
+		} else {
+			fmt.Fprint(w, "    |")
+		}
+		for c := 0; c < st.Cols; c++ {
+			last := r == st.LastRow && c == st.LastCol
+			fmt.Fprint(w, tokenText(st.Board[r][c], last, winning[[2]int{r, c}], color))
+		}
+		fmt.Fprintln(w, "|")
+	}
+	fmt.Fprintln(w, "    +"+strings.Repeat("-", 3*st.Cols)+"+")
+	if st.Timed {
+		fmt.Fprintf(w, "    Pendule: %s %s  -  %s %s\n",
+			st.Players[0], formatPlayClock(st.TimeLeftMs[0]), st.Players[1], formatPlayClock(st.TimeLeftMs[1]))
+	}
+}
+
+// formatPlayClock affiche un temps restant comme la pendule de la page de jeu.
+func formatPlayClock(ms int64) string {
+	total := (ms + 999) / 1000
+	if total < 0 {
+		total = 0
+	}
+	switch {
+	case total >= 86400:
+		return fmt.Sprintf("%d j %d h", total/86400, total%86400/3600)
+	case total >= 3600:
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// playLoop enchaîne les parties: affichage, saisie d'un numéro de colonne (ou de ligne), "n" pour une
+// nouvelle partie, "q" pour quitter.
+func playLoop(backend playBackend, in io.Reader, out io.Writer, color bool) error {
+	st, err := backend.New()
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(in)
+	for {
+		drawBoard(out, st, color)
+		axis := "colonne"
+		if st.Gravity == "left" || st.Gravity == "right" {
+			axis = "ligne"
+		}
+		if st.GameOver {
+			fmt.Fprintln(out, "  ", st.Message)
+			fmt.Fprint(out, "Nouvelle partie (n) ou quitter (q) ? ")
+		} else {
+			fmt.Fprintf(out, "%s %s, choisissez une %s (1-%d, q pour quitter) : ",
+				tokenText(st.CurrentPlayer, false, false, color), st.Players[st.CurrentPlayer-1], axis, slotCount(st))
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		input := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch input {
+		case "q", "quit", "quitter":
+			return nil
+		case "n", "new", "nouvelle":
+			if st, err = backend.New(); err != nil {
+				return err
+			}
+			continue
+		}
+		if st.GameOver {
+			continue
+		}
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 1 || n > slotCount(st) {
+			fmt.Fprintf(out, "Saisie invalide: entrez un numéro de %s entre 1 et %d.\n", axis, slotCount(st))
+			continue
+		}
+		next, err := backend.Play(n - 1)
+		if err != nil {
+			fmt.Fprintln(out, "Coup refusé:", err)
+			continue
+		}
+		st = next
+	}
+}
+
+// slotCount retourne le nombre d'emplacements jouables: colonnes, ou lignes en gravité latérale.
+func slotCount(st GameState) int {
+	if st.Gravity == "left" || st.Gravity == "right" {
+		return st.Rows
+	}
+	return st.Cols
+}
+
+// runPlay lance le client terminal: contre l'IA ou à deux, en local ou sur un serveur distant (-remote).
+func runPlay(args []string) error {
+	fset := flag.NewFlagSet("play", flag.ExitOnError)
+	difficulty := fset.String("difficulty", "easy", "plateau: easy (6x7), normal (7x8) ou hard (8x10)")
+	mode := fset.String("mode", "normal", "gravité: normal, inverse, gauche, droite ou rotation")
+	ai := fset.String("ai", "medium", "adversaire: easy, medium, hard, profil d'IA ou bot; vide pour jouer à deux")
+	name1 := fset.String("name", "Joueur 1", "pseudo du joueur 1")
+	name2 := fset.String("name2", "", "pseudo du joueur 2 (\"IA\" contre l'IA)")
+	cadence := fset.String("cadence", "", "cadence (ex: 3+2, coup-15)")
+	color := fset.String("color", "auto", "couleurs ANSI: auto, always ou never")
+	remote := fset.String("remote", "", "adresse d'un serveur Puissance 4 (ex: http://localhost:8081); local par défaut")
+	fset.Parse(args)
+
+	settings := playSettings{
+		Difficulty: *difficulty,
+		Mode:       *mode,
+		AI:         *ai,
+		Name1:      *name1,
+		Name2:      *name2,
+		Cadence:    *cadence,
+	}
+	if settings.AI == "" && settings.Name2 == "" {
+		settings.Name2 = "Joueur 2"
+	}
+	var backend playBackend
+	if *remote != "" {
+		rb, err := newRemoteBackend(*remote, settings)
+		if err != nil {
+			return err
+		}
+		backend = rb
+	} else {
+		if settings.AI != "" && !knownOpponent(settings.AI) {
+			return fmt.Errorf("adversaire inconnu: %q", settings.AI)
+		}
+		backend = &localBackend{settings: settings}
+	}
+	return playLoop(backend, os.Stdin, os.Stdout, useColor(*color))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlayLoopLocal(t *testing.T) {
+	backend := &localBackend{settings: playSettings{Difficulty: "easy", Mode: "normal", Name1: "Alice", Name2: "Bob"}}
+	// Alice aligne quatre jetons dans la colonne 1; "9" est hors du plateau
+	in := strings.NewReader("9\n1\n2\n1\n2\n1\n2\n1\nq\n")
+	var out strings.Builder
+	if err := playLoop(backend, in, &out, false); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	if !strings.Contains(text, "Saisie invalide: entrez un numéro de colonne entre 1 et 7.") {
+		t.Error("saisie hors du plateau acceptée")
+	}
+	g := backend.game
+	if !g.GameOver || g.Winner != 1 || len(g.History) != 7 {
+		t.Fatalf("partie: GameOver=%v Winner=%d, %d coups", g.GameOver, g.Winner, len(g.History))
+	}
+	if !strings.Contains(text, "Nouvelle partie (n) ou quitter (q) ?") {
+		t.Error("fin de partie non proposée")
+	}
+	// Les jetons gagnants sont entre parenthèses
+	if n := strings.Count(text, "(X)"); n != 4 {
+		t.Errorf("%d jetons gagnants affichés, attendu 4", n)
+	}
+}
+
+func TestFormatPlayClock(t *testing.T) {
+	tests := []struct {
+		ms   int64
+		want string
+	}{
+		{-5, "0:00"},
+		{1, "0:01"},
+		{65000, "1:05"},
+		{3723000, "1:02:03"},
+		{2*86400000 + 5*3600000, "2 j 5 h"},
+	}
+	for _, tt := range tests {
+		if got := formatPlayClock(tt.ms); got != tt.want {
+			t.Errorf("formatPlayClock(%d) = %q, attendu %q", tt.ms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// EvalWeights sont les poids de evaluateWindow pour 2, 3 et 4 jetons alignés dans une fenêtre.
+type EvalWeights struct {
+	Two   int `json:"two"`
+	Three int `json:"three"`
+	Four  int `json:"four"`
+}
+
+var defaultWeights = EvalWeights{Two: 2, Three: 10, Four: 100}
+
+// AIProfile décrit la personnalité et la force d'une IA.
+type AIProfile struct {
+	Name        string      `json:"name"`          // identifiant utilisé dans l'URL (ailevel=...)
+	Label       string      `json:"label"`         // libellé affiché
+	Rank        int         `json:"rank"`          // ordre dans l'échelle de difficulté
+	Depth       int         `json:"depth"`         // profondeur maximale de recherche
+	TimeLimitMs int         `json:"time_limit_ms"` // temps de réflexion maximal par coup (0 = sans limite)
+	Weights     EvalWeights `json:"weights"`
+	Temperature float64     `json:"temperature"`  // 0 = toujours le meilleur coup, sinon tirage pondéré par les scores
+	MistakeRate float64     `json:"mistake_rate"` // probabilité de jouer un coup au hasard
+	UseBook     bool        `json:"use_book"`     // consulte la bibliothèque d'ouvertures
+}
+
+// aiProfilesPath est le fichier de profils chargé au démarrage s'il existe.
+const aiProfilesPath = "ai_profiles.json"
+
+// aiProfiles contient les profils disponibles, indexés par nom. Les trois niveaux historiques
+// y figurent toujours et peuvent être redéfinis par le fichier de configuration.
+var aiProfiles = map[string]*AIProfile{
+	"easy":   {Name: "easy", Label: "Facile", Rank: 10, Depth: 1, Weights: defaultWeights, MistakeRate: 1},
+	"medium": {Name: "medium", Label: "Moyen", Rank: 20, Depth: 2, Weights: defaultWeights, Temperature: 1000},
+	"hard":   {Name: "hard", Label: "Difficile", Rank: 30, Depth: 4, Weights: defaultWeights, UseBook: true},
+}
+
+// profileForLevel retourne le profil par défaut d'un niveau d'IA.
+func profileForLevel(level AILevel) *AIProfile {
+	switch level {
+	case AIMedium:
+		return aiProfiles["medium"]
+	case AIHard:
+		return aiProfiles["hard"]
+	default:
+		return aiProfiles["easy"]
+	}
+}
+
+// lookupProfile retourne le profil nommé, ou celui du niveau donné si le nom est inconnu.
+func lookupProfile(name string, level AILevel) *AIProfile {
+	if p, ok := aiProfiles[name]; ok {
+		return p
+	}
+	return profileForLevel(level)
+}
+
+// sortedProfiles retourne les profils dans l'ordre de l'échelle de difficulté.
+func sortedProfiles() []*AIProfile {
+	list := make([]*AIProfile, 0, len(aiProfiles))
+	for _, p := range aiProfiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Rank != list[j].Rank {
+			return list[i].Rank < list[j].Rank
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// loadAIProfiles lit une liste de profils JSON et les ajoute (ou les substitue) aux profils existants.
+// Un fichier absent n'est pas une erreur.
+func loadAIProfiles(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []*AIProfile
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, p := range list {
+		if p.Name == "" {
+			return fmt.Errorf("%s: profil sans nom", path)
+		}
+		if p.Label == "" {
+			p.Label = p.Name
+		}
+		if p.Depth < 1 {
+			p.Depth = 1
+		}
+		if p.Weights == (EvalWeights{}) {
+			p.Weights = defaultWeights
+		}
+		aiProfiles[p.Name] = p
+	}
+	return nil
+}
+
+// aiProfile retourne le profil utilisé par l'IA de la partie.
+func (g *Game) aiProfile() *AIProfile {
+	if g.Profile != nil {
+		return g.Profile
+	}
+	return profileForLevel(g.AILevel)
+}
+
+// evalWeights retourne les poids d'évaluation du profil de la partie.
+func (g *Game) evalWeights() EvalWeights {
+	if g.Profile != nil {
+		return g.Profile.Weights
+	}
+	return defaultWeights
+}
+
+// profileMove choisit le coup de l'IA selon son profil: erreur volontaire, bibliothèque d'ouvertures,
+// puis recherche limitée en profondeur et en temps, et enfin tirage selon la température.
+func (g *Game) profileMove(p *AIProfile) int {
+	moves := g.getValidMoves()
+	if len(moves) == 0 {
+		return -1
+	}
+	if p.MistakeRate > 0 && rand.Float64() < p.MistakeRate {
+		g.lastAI = &aiMoveInfo{Source: "hasard"}
+		return moves[rand.Intn(len(moves))]
+	}
+	if p.UseBook {
+		if col, ok := openingBook.lookup(g); ok {
+			g.lastAI = &aiMoveInfo{Source: "livre"}
+			return col
+		}
+	}
+
+	// Le temps de réflexion est borné par le profil, par le plafond du serveur et par la pendule de l'IA
+	var limit time.Duration
+	if p.TimeLimitMs > 0 {
+		limit = time.Duration(p.TimeLimitMs) * time.Millisecond
+	}
+	if aiTimeLimit > 0 && (limit == 0 || aiTimeLimit < limit) {
+		limit = aiTimeLimit
+	}
+	if deadline := g.aiDeadline(); !deadline.IsZero() {
+		if left := time.Until(deadline); limit == 0 || left < limit {
+			limit = left
+		}
+		if limit < time.Millisecond {
+			limit = time.Millisecond
+		}
+	}
+	// La recherche évalue avec les poids du profil, même s'il n'est pas celui de la partie (auto-jeu)
+	pos := g
+	if g.Profile != p {
+		pos = g.clone()
+		pos.Profile = p
+	}
+	scores, depth := analyseMoves(pos, p.Depth, limit)
+	if len(scores) == 0 {
+		return moves[0]
+	}
+	slot := bestSlot(scores)
+	if p.Temperature > 0 {
+		slot = sampleMove(scores, p.Temperature)
+	}
+	g.lastAI = &aiMoveInfo{Source: "recherche", Depth: depth}
+	for _, s := range scores {
+		if s.Slot == slot {
+			g.lastAI.Score = s.Score
+		}
+	}
+	return slot
+}
+
+// sampleMove tire un coup avec une probabilité proportionnelle à exp(score / température).
+func sampleMove(scores []MoveScore, temperature float64) int {
+	best := -infScore
+	for _, s := range scores {
+		best = max(best, s.Score)
+	}
+	weights := make([]float64, len(scores))
+	total := 0.0
+	for i, s := range scores {
+		weights[i] = math.Exp(float64(s.Score-best) / temperature)
+		total += weights[i]
+	}
+	pick := rand.Float64() * total
+	for i, w := range weights {
+		if pick < w {
+			return scores[i].Slot
+		}
+		pick -= w
+	}
+	return scores[len(scores)-1].Slot
+}
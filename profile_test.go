@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// restoreProfiles remet les profils d'origine à la fin du test.
+func restoreProfiles(t *testing.T) {
+	saved := map[string]*AIProfile{}
+	for name, p := range aiProfiles {
+		saved[name] = p
+	}
+	t.Cleanup(func() { aiProfiles = saved })
+}
+
+func TestLoadAIProfiles(t *testing.T) {
+	restoreProfiles(t)
+	if err := loadAIProfiles("ai_profiles.example.json"); err != nil {
+		t.Fatal(err)
+	}
+	p, ok := aiProfiles["debutant"]
+	if !ok {
+		t.Fatal("profil de l'exemple non chargé")
+	}
+	// Poids absents: ceux par défaut
+	if p.Weights != defaultWeights || p.Label != "Débutant" || p.Depth != 2 {
+		t.Errorf("profil chargé: %+v", p)
+	}
+	if aiProfiles["easy"] == nil || aiProfiles["hard"] == nil {
+		t.Error("niveaux intégrés perdus")
+	}
+	list := sortedProfiles()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Rank > list[i].Rank {
+			t.Fatalf("profils non triés: %s (%d) avant %s (%d)", list[i-1].Name, list[i-1].Rank, list[i].Name, list[i].Rank)
+		}
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name, data string
+		ok         bool
+	}{
+		{"sans nom", `[{"label": "Anonyme"}]`, false},
+		{"JSON invalide", `[{"name": }]`, false},
+		{"valeurs par défaut", `[{"name": "minimal", "depth": 0}]`, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "profils.json")
+		os.WriteFile(path, []byte(tt.data), 0o644)
+		if err := loadAIProfiles(path); (err == nil) != tt.ok {
+			t.Errorf("%s: erreur %v", tt.name, err)
+		}
+	}
+	if p := aiProfiles["minimal"]; p == nil || p.Depth != 1 || p.Label != "minimal" {
+		t.Errorf("profil minimal: %+v", p)
+	}
+	if err := loadAIProfiles(filepath.Join(dir, "absent.json")); err != nil {
+		t.Errorf("fichier absent: %v", err)
+	}
+}
+
+func TestProfileMove(t *testing.T) {
+	g := threatGame(1)
+	strict := &AIProfile{Name: "strict", Depth: 2, Weights: defaultWeights}
+	if slot := g.profileMove(strict); slot != 3 {
+		t.Fatalf("coup %d, attendu le coup gagnant 3", slot)
+	}
+	if g.lastAI == nil || g.lastAI.Source != "recherche" || g.lastAI.Depth != 2 {
+		t.Errorf("calcul du coup: %+v", g.lastAI)
+	}
+
+	random := &AIProfile{Name: "hasard", Depth: 2, Weights: defaultWeights, MistakeRate: 1}
+	for range 20 {
+		if slot := g.profileMove(random); slot < 0 || slot >= g.Cols {
+			t.Fatalf("coup au hasard %d hors du plateau", slot)
+		}
+	}
+	if g.lastAI.Source != "hasard" {
+		t.Errorf("source %q, attendu hasard", g.lastAI.Source)
+	}
+}
+
+func TestSampleMove(t *testing.T) {
+	scores := []MoveScore{{Slot: 0, Score: 0}, {Slot: 1, Score: 50}, {Slot: 2, Score: -20}}
+	// Température faible: le meilleur coup presque toujours
+	for range 50 {
+		if slot := sampleMove(scores, 0.5); slot != 1 {
+			t.Fatalf("coup %d tiré à basse température", slot)
+		}
+	}
+	// Température élevée: tous les coups sortent
+	seen := map[int]bool{}
+	for range 500 {
+		seen[sampleMove(scores, 1e6)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("coups tirés à haute température: %v", seen)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limites du serveur, réglées par les options de serve (voir ServerConfig). Une limite nulle est désactivée.
+var (
+	ipLimiter      *rateLimiter   // requêtes par adresse IP
+	sessionLimiter *rateLimiter   // requêtes POST par visiteur (cookie de joueur)
+	aiSearches     *searchLimiter // recherches de l'IA simultanées
+	maxBodyBytes   int64          // taille maximale du corps d'une requête
+	trustedProxies []netip.Prefix // proxys dont l'en-tête X-Forwarded-For est cru
+)
+
+// retryAfterSeconds est le délai conseillé aux clients refusés (en-tête Retry-After).
+const retryAfterSeconds = 2
+
+var (
+	errRateLimited = errors.New("trop de requêtes: patientez quelques secondes avant de recommencer")
+	errServerBusy  = errors.New("le serveur calcule déjà trop de coups: réessayez dans quelques secondes")
+)
+
+// rateLimiter limite le débit de requêtes par clé (seau à jetons): rate requêtes par seconde en moyenne,
+// burst d'affilée.
+type rateLimiter struct {
+	rate, burst float64
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	pruned      time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter retourne une limite de rate requêtes par seconde, nil si rate est nul (pas de limite).
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}, pruned: time.Now()}
+}
+
+// allow consomme un jeton de la clé key; faux si le seau est vide.
+func (l *rateLimiter) allow(key string) bool {
+	if l == nil {
+		return true
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.pruned) > time.Minute {
+		l.prune(now)
+	}
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune oublie les seaux redevenus pleins: ils se comportent comme des seaux neufs.
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.pruned = now
+}
+
+// searchLimiter plafonne les recherches de l'IA simultanées. Au-delà, les demandes attendent dans
+// une file d'au plus queue places, pendant wait au plus; une file pleine est refusée aussitôt.
+type searchLimiter struct {
+	slots   chan struct{}
+	queue   int64
+	wait    time.Duration
+	waiting atomic.Int64
+}
+
+// newSearchLimiter retourne une limite de n recherches simultanées, nil si n est nul (pas de limite).
+func newSearchLimiter(n, queue int, wait time.Duration) *searchLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &searchLimiter{slots: make(chan struct{}, n), queue: int64(queue), wait: wait}
+}
+
+// acquire réserve une place de recherche; release la libère. Retourne errServerBusy si la file
+// est pleine ou si l'attente dépasse le délai.
+func (l *searchLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if release, ok := l.tryAcquire(); ok {
+		return release, nil
+	}
+	release = func() { <-l.slots }
+	if l.waiting.Add(1) > l.queue {
+		l.waiting.Add(-1)
+		return nil, errServerBusy
+	}
+	defer l.waiting.Add(-1)
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, errServerBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tryAcquire réserve une place de recherche si l'une est libre, sans attendre.
+func (l *searchLimiter) tryAcquire() (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// active retourne le nombre de recherches en cours.
+func (l *searchLimiter) active() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// queued retourne le nombre de demandes en attente.
+func (l *searchLimiter) queued() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.waiting.Load()
+}
+
+// acquireSearch réserve une place de recherche pour la requête; en cas de refus, la réponse 429
+// est écrite et ok est faux. L'appelant libère la place par release.
+func acquireSearch(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	release, err := aiSearches.acquire(r.Context())
+	if err != nil {
+		rejectRequest(w, r, "search", err)
+		return nil, false
+	}
+	return release, true
+}
+
+// acquireSearch réserve une place de recherche pour une requête qui tient le verrou de la session.
+// Faute de place libre, le verrou est relâché pendant l'attente pour ne pas bloquer les autres pages
+// de la partie: au retour, l'appelant revérifie la partie, qui a pu changer entre-temps.
+func (s *GameSession) acquireSearch(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if release, ok := aiSearches.tryAcquire(); ok {
+		return release, true
+	}
+	s.mu.Unlock()
+	defer s.mu.Lock()
+	return acquireSearch(w, r)
+}
+
+// rejectRequest répond 429 à une requête refusée par la limite limit ("ip", "session" ou "search"):
+// page d'erreur pour un navigateur, texte pour l'API et les appels des scripts.
+func rejectRequest(w http.ResponseWriter, r *http.Request, limit string, err error) {
+	rateLimited.inc(limit)
+	logger(r.Context()).Warn("requête refusée", "limit", limit, "path", r.URL.Path, "remote", r.RemoteAddr, "err", err)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		renderError(w, http.StatusTooManyRequests, "Trop de requêtes", err, "")
+		return
+	}
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// parseTrustedProxies lit la liste des proxys de confiance: adresses IP ou réseaux CIDR séparés par des virgules.
+func parseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(item); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("proxy de confiance invalide: %q", item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// trustedProxy indique si l'adresse est celle d'un proxy de confiance.
+func trustedProxy(addr netip.Addr) bool {
+	for _, p := range trustedProxies {
+		if p.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP retourne l'adresse IP du client (sans le port). Derrière un proxy de confiance, c'est la
+// dernière adresse de X-Forwarded-For qui n'est pas celle d'un proxy de confiance: les adresses
+// précédentes, fournies par le client, ne sont pas crues.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !trustedProxy(addr) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		host = hop.Unmap().String()
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// visitorKey retourne la clé du débit par visiteur: l'identifiant du joueur connu du serveur, ou
+// l'adresse IP si le cookie est absent ou inconnu. Le cookie seul, choisi par le client, ne sert
+// pas de clé: un cookie neuf à chaque requête ne doit ni contourner la limite ni remplir la table.
+func visitorKey(r *http.Request) string {
+	if p := knownPlayer(r); p != nil {
+		return "player:" + p.ID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// limit applique les limites du serveur à une page: taille du corps, débit par adresse IP et,
+// pour les requêtes qui modifient une partie (POST), débit par visiteur.
+func limit(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maxBodyBytes > 0 {
+			if r.ContentLength > maxBodyBytes {
+				http.Error(w, "Requête trop volumineuse", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+			// Le formulaire d'une requête avec un corps est lu ici: un corps tronqué par la limite
+			// laisserait sinon aux pages un formulaire vide, refusé pour une autre raison (jeton anti-CSRF absent...)
+			var tooLarge *http.MaxBytesError
+			if r.ContentLength != 0 {
+				if err := r.ParseForm(); errors.As(err, &tooLarge) {
+					http.Error(w, "Requête trop volumineuse", http.StatusRequestEntityTooLarge)
+					return
+				}
+			}
+		}
+		if !ipLimiter.allow(clientIP(r)) {
+			rejectRequest(w, r, "ip", errRateLimited)
+			return
+		}
+		if r.Method != "GET" && r.Method != "HEAD" && !sessionLimiter.allow(visitorKey(r)) {
+			rejectRequest(w, r, "session", errRateLimited)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if l := newRateLimiter(0, 10); l != nil || !l.allow("x") {
+		t.Fatal("une limite nulle doit tout laisser passer")
+	}
+
+	l := newRateLimiter(50, 3)
+	for i := range 3 {
+		if !l.allow("a") {
+			t.Fatalf("requête %d de la rafale refusée", i+1)
+		}
+	}
+	if l.allow("a") {
+		t.Fatal("requête au-delà de la rafale acceptée")
+	}
+	if !l.allow("b") {
+		t.Fatal("les clés ne partagent pas leur seau")
+	}
+	time.Sleep(50 * time.Millisecond) // 2,5 jetons regagnés
+	if !l.allow("a") || !l.allow("a") {
+		t.Fatal("le seau ne se remplit pas")
+	}
+
+	l.prune(time.Now().Add(time.Minute))
+	if len(l.buckets) != 0 {
+		t.Fatalf("%d seaux pleins conservés", len(l.buckets))
+	}
+}
+
+func TestSearchLimiter(t *testing.T) {
+	release, err := (*searchLimiter)(nil).acquire(context.Background())
+	if err != nil {
+		t.Fatalf("sans limite: %v", err)
+	}
+	release()
+
+	l := newSearchLimiter(1, 1, 50*time.Millisecond)
+	release, err = l.acquire(context.Background())
+	if err != nil || l.active() != 1 {
+		t.Fatalf("première recherche: %v, %d en cours", err, l.active())
+	}
+
+	// Une demande attend dans la file; la suivante est refusée aussitôt
+	waited := make(chan error, 1)
+	go func() {
+		r, err := l.acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		waited <- err
+	}()
+	for l.queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	if _, err := l.acquire(context.Background()); !errors.Is(err, errServerBusy) || time.Since(start) >= l.wait {
+		t.Fatalf("file pleine: %v après %v", err, time.Since(start))
+	}
+	release()
+	if err := <-waited; err != nil {
+		t.Fatalf("la demande en file n'a pas obtenu la place libérée: %v", err)
+	}
+
+	// Délai d'attente dépassé, puis requête annulée
+	release, _ = l.acquire(context.Background())
+	if _, err := l.acquire(context.Background()); !errors.Is(err, errServerBusy) {
+		t.Fatalf("attente dépassée: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("requête annulée: %v", err)
+	}
+	release()
+	if l.active() != 0 || l.queued() != 0 {
+		t.Fatalf("%d en cours, %d en attente après libération", l.active(), l.queued())
+	}
+}
+
+func TestSessionSearchUnlocksWhileQueued(t *testing.T) {
+	saved := aiSearches
+	aiSearches = newSearchLimiter(1, 1, 2*time.Second)
+	t.Cleanup(func() { aiSearches = saved })
+	busy, _ := aiSearches.acquire(context.Background())
+
+	sess := &GameSession{}
+	got := make(chan bool, 1)
+	go func() {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		release, ok := sess.acquireSearch(httptest.NewRecorder(), httptest.NewRequest("POST", "/ai-move", nil))
+		if ok {
+			release()
+		}
+		got <- ok
+	}()
+	for aiSearches.queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Pendant l'attente, les autres pages de la partie prennent le verrou
+	if !sess.mu.TryLock() {
+		t.Fatal("verrou de la session gardé pendant l'attente d'une place")
+	}
+	sess.mu.Unlock()
+	busy()
+	if !<-got {
+		t.Fatal("la demande en file n'a pas obtenu la place libérée")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseTrustedProxies("10.0.0.0/8,inconnu"); err == nil {
+		t.Fatal("proxy invalide accepté")
+	}
+	defer func(saved []netip.Prefix) { trustedProxies = saved }(trustedProxies)
+	trustedProxies = proxies
+
+	tests := []struct {
+		name, remote, forwarded, want string
+	}{
+		{"sans proxy", "203.0.113.5:1234", "", "203.0.113.5"},
+		{"en-tête d'un client non fiable ignoré", "203.0.113.5:1234", "198.51.100.1", "203.0.113.5"},
+		{"proxy de confiance", "127.0.0.1:80", "198.51.100.1", "198.51.100.1"},
+		{"adresses fournies par le client ignorées", "127.0.0.1:80", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chaîne de proxys", "127.0.0.1:80", "198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"en-tête invalide", "127.0.0.1:80", "n'importe quoi", "127.0.0.1"},
+		{"IPv6", "[2001:db8::1]:443", "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, attendu %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimitMiddleware(t *testing.T) {
+	defer func(ip, session *rateLimiter, body int64) {
+		ipLimiter, sessionLimiter, maxBodyBytes = ip, session, body
+	}(ipLimiter, sessionLimiter, maxBodyBytes)
+	ipLimiter, sessionLimiter, maxBodyBytes = newRateLimiter(1, 2), newRateLimiter(1, 1), 100
+
+	h := limit(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	do := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+	post := func(body string, chunked bool) *http.Request {
+		r := httptest.NewRequest("POST", "/connect4", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if chunked {
+			r.ContentLength = -1
+		}
+		r.RemoteAddr = "203.0.113.9:1"
+		return r
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"corps annoncé trop long", post("col="+strings.Repeat("1", 200), false), http.StatusRequestEntityTooLarge},
+		{"corps trop long sans longueur", post("col="+strings.Repeat("1", 200), true), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if got := do(tt.req); got != tt.code {
+			t.Errorf("%s: %d, attendu %d", tt.name, got, tt.code)
+		}
+	}
+
+	// Débit par visiteur (POST) puis par adresse IP
+	p := &Player{ID: newID()}
+	mutex.Lock()
+	players[p.ID] = p
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(players, p.ID)
+		mutex.Unlock()
+	})
+	withCookie := func(r *http.Request, id string) *http.Request {
+		r.AddCookie(&http.Cookie{Name: playerCookie, Value: id})
+		r.RemoteAddr = "198.51.100.7:1"
+		return r
+	}
+	ipLimiter = newRateLimiter(1, 10)
+	if got := do(withCookie(post("col=1", false), p.ID)); got != http.StatusOK {
+		t.Fatalf("premier coup: %d", got)
+	}
+	if got := do(withCookie(post("col=2", false), p.ID)); got != http.StatusTooManyRequests {
+		t.Fatalf("coup au-delà de la limite par visiteur: %d", got)
+	}
+	// Un cookie inconnu compte pour l'adresse IP: en changer ne remet pas la limite à zéro
+	if got := do(withCookie(post("col=3", false), "inconnu-1")); got != http.StatusOK {
+		t.Fatalf("premier coup sans joueur connu: %d", got)
+	}
+	if got := do(withCookie(post("col=4", false), "inconnu-2")); got != http.StatusTooManyRequests {
+		t.Fatalf("nouveau cookie au-delà de la limite: %d", got)
+	}
+	if got := do(withCookie(post("col=5", false), "")); got != http.StatusTooManyRequests {
+		t.Fatalf("requête sans joueur au-delà de la limite: %d", got)
+	}
+
+	ipLimiter = newRateLimiter(1, 1)
+	get := httptest.NewRequest("GET", "/lobby", nil)
+	get.RemoteAddr = "198.51.100.7:1"
+	if got := do(get); got != http.StatusOK {
+		t.Fatalf("première page: %d", got)
+	}
+	// Sans corps, le formulaire n'est pas lu
+	if get.Form != nil {
+		t.Error("formulaire lu pour une requête GET")
+	}
+	get = httptest.NewRequest("GET", "/lobby", nil)
+	get.RemoteAddr = "198.51.100.7:1"
+	if got := do(get); got != http.StatusTooManyRequests {
+		t.Fatalf("requête au-delà de la limite par adresse IP: %d", got)
+	}
+}
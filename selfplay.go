@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tunedProfilePath est le fichier de profils écrit par défaut par selfplay, dans le répertoire des données.
+const tunedProfilePath = "tuned_profile.json"
+
+// selfPlayConfig est une configuration de plateau jouée pendant l'auto-jeu.
+type selfPlayConfig struct {
+	Rows, Cols int
+	Mode       string
+}
+
+func (c selfPlayConfig) String() string {
+	return boardSizeKey(c.Rows, c.Cols) + " " + c.Mode
+}
+
+// ConfigStats compte les résultats d'un profil contre un autre, vus du premier.
+type ConfigStats struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Draws   int     `json:"draws"`
+	WinRate float64 `json:"win_rate"` // nuls comptés pour moitié
+}
+
+func (s *ConfigStats) add(result int) {
+	s.Games++
+	switch result {
+	case 1:
+		s.Wins++
+	case -1:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+	s.WinRate = (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games)
+}
+
+// SelfPlayStats résume le réglage et la vérification finale contre les poids par défaut.
+type SelfPlayStats struct {
+	Iterations int                     `json:"iterations"`
+	Baseline   EvalWeights             `json:"baseline"`
+	Total      ConfigStats             `json:"total"`
+	ByConfig   map[string]*ConfigStats `json:"by_config"`
+}
+
+// playHeadless joue une partie IA contre IA sans interface et retourne le vainqueur (0 pour un nul).
+// Les openingPlies premiers demi-coups sont joués au hasard pour varier les parties.
+func playHeadless(cfg selfPlayConfig, p1, p2 *AIProfile, starter, openingPlies int) int {
+	g := NewGame(cfg.Rows, cfg.Cols, 0, "", p1.Name, p2.Name, cfg.Mode, "", ModeHumanVsHuman, AIEasy)
+	g.CurrentPlayer = starter
+	for !g.GameOver {
+		p := p1
+		if g.CurrentPlayer == 2 {
+			p = p2
+		}
+		var slot int
+		if g.TurnCount < openingPlies {
+			moves := g.getValidMoves()
+			if len(moves) == 0 {
+				break
+			}
+			slot = moves[rand.Intn(len(moves))]
+		} else {
+			slot = g.profileMove(p)
+		}
+		if slot < 0 || !g.DropToken(slot) {
+			break
+		}
+	}
+	return g.Winner
+}
+
+// playSeries joue des paires de parties (chaque profil commence une fois) en parallèle sur toutes les
+// configurations et retourne les statistiques du point de vue de a.
+func playSeries(configs []selfPlayConfig, a, b *AIProfile, pairs, openingPlies int) (ConfigStats, map[string]*ConfigStats) {
+	type job struct {
+		cfg  selfPlayConfig
+		swap bool
+	}
+	jobs := make(chan job)
+	var (
+		mu       sync.Mutex
+		total    ConfigStats
+		byConfig = map[string]*ConfigStats{}
+		wg       sync.WaitGroup
+	)
+	for _, cfg := range configs {
+		byConfig[cfg.String()] = &ConfigStats{}
+	}
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				// a joue toujours les jetons du joueur 1; on alterne qui commence
+				starter := 1
+				if j.swap {
+					starter = 2
+				}
+				result := 0
+				switch playHeadless(j.cfg, a, b, starter, openingPlies) {
+				case 1:
+					result = 1
+				case 2:
+					result = -1
+				}
+				mu.Lock()
+				total.add(result)
+				byConfig[j.cfg.String()].add(result)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < pairs; i++ {
+		cfg := configs[i%len(configs)]
+		jobs <- job{cfg: cfg, swap: false}
+		jobs <- job{cfg: cfg, swap: true}
+	}
+	close(jobs)
+	wg.Wait()
+	return total, byConfig
+}
+
+// parseSelfPlayConfigs construit le produit tailles x modes.
+func parseSelfPlayConfigs(sizes, modes string) ([]selfPlayConfig, error) {
+	var configs []selfPlayConfig
+	for _, size := range strings.Split(sizes, ",") {
+		rowsStr, colsStr, _ := strings.Cut(strings.TrimSpace(size), "x")
+		rows, err1 := strconv.Atoi(rowsStr)
+		cols, err2 := strconv.Atoi(colsStr)
+		if err1 != nil || err2 != nil || rows < 4 || cols < 4 {
+			return nil, fmt.Errorf("taille invalide: %q", size)
+		}
+		for _, mode := range strings.Split(modes, ",") {
+			configs = append(configs, selfPlayConfig{Rows: rows, Cols: cols, Mode: strings.TrimSpace(mode)})
+		}
+	}
+	return configs, nil
+}
+
+// runSelfPlay règle les poids d'évaluation par SPSA en auto-jeu, vérifie le résultat contre les poids
+// par défaut et écrit un fichier de profils (compatible avec ai_profiles.json) accompagné des statistiques.
+// Usage: power4 selfplay [-iterations N] [-pairs N] [-depth N] [-sizes 6x7,7x8] [-modes normal,inverse] [-out fichier]
+func runSelfPlay(args []string) error {
+	fset := flag.NewFlagSet("selfplay", flag.ExitOnError)
+	iterations := fset.Int("iterations", 40, "nombre d'itérations SPSA")
+	pairs := fset.Int("pairs", 50, "paires de parties par itération")
+	verify := fset.Int("verify", 500, "paires de parties de vérification contre les poids par défaut")
+	depth := fset.Int("depth", 3, "profondeur de recherche des deux IA")
+	openingPlies := fset.Int("random-plies", 2, "demi-coups joués au hasard en début de partie")
+	sizes := fset.String("sizes", "6x7,7x8", "tailles de plateau (lignes x colonnes)")
+	modes := fset.String("modes", "normal,inverse", "modes de gravité")
+	step := fset.Float64("step", 1.5, "amplitude des perturbations SPSA")
+	rate := fset.Float64("rate", 20, "pas d'apprentissage SPSA")
+	name := fset.String("name", "tuned", "nom du profil produit")
+	out := fset.String("out", dataPath(tunedProfilePath), "fichier de sortie")
+	fset.Parse(args)
+
+	configs, err := parseSelfPlayConfigs(*sizes, *modes)
+	if err != nil {
+		return err
+	}
+
+	// θ = (Two, Three); Four reste fixe car une victoire est détectée par la recherche elle-même
+	theta := []float64{float64(defaultWeights.Two), float64(defaultWeights.Three)}
+	weightsOf := func(t []float64) EvalWeights {
+		two := max(0, int(math.Round(t[0])))
+		three := max(two+1, int(math.Round(t[1])))
+		return EvalWeights{Two: two, Three: three, Four: max(defaultWeights.Four, three+1)}
+	}
+	profileOf := func(n string, w EvalWeights) *AIProfile {
+		return &AIProfile{Name: n, Label: n, Depth: *depth, Weights: w}
+	}
+
+	for k := 1; k <= *iterations; k++ {
+		// Gains décroissants classiques de SPSA
+		ck := *step / math.Pow(float64(k), 0.101)
+		ak := *rate / math.Pow(float64(k)+5, 0.602)
+		delta := make([]float64, len(theta))
+		plus := make([]float64, len(theta))
+		minus := make([]float64, len(theta))
+		for i := range theta {
+			delta[i] = 1
+			if rand.Intn(2) == 0 {
+				delta[i] = -1
+			}
+			plus[i] = theta[i] + ck*delta[i]
+			minus[i] = theta[i] - ck*delta[i]
+		}
+		stats, _ := playSeries(configs, profileOf("plus", weightsOf(plus)), profileOf("minus", weightsOf(minus)), *pairs, *openingPlies)
+		// Score centré: > 0 si θ+ bat θ-
+		r := 2*stats.WinRate - 1
+		for i := range theta {
+			theta[i] += ak * r / (2 * ck * delta[i])
+			theta[i] = math.Max(theta[i], 0)
+		}
+		fmt.Printf("itération %d/%d: θ+ %.1f%% contre θ-, poids %+v\n", k, *iterations, 100*stats.WinRate, weightsOf(theta))
+	}
+
+	tuned := weightsOf(theta)
+	total, byConfig := playSeries(configs, profileOf(*name, tuned), profileOf("default", defaultWeights), *verify, *openingPlies)
+	fmt.Printf("vérification: %d parties, %.1f%% contre les poids par défaut\n", total.Games, 100*total.WinRate)
+
+	result := []struct {
+		*AIProfile
+		Stats SelfPlayStats `json:"stats"`
+	}{{
+		AIProfile: &AIProfile{Name: *name, Label: *name, Rank: 35, Depth: *depth, Weights: tuned},
+		Stats: SelfPlayStats{
+			Iterations: *iterations,
+			Baseline:   defaultWeights,
+			Total:      total,
+			ByConfig:   byConfig,
+		},
+	}}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestConfigStatsAdd(t *testing.T) {
+	var s ConfigStats
+	for _, result := range []int{1, 1, 0, -1} {
+		s.add(result)
+	}
+	if s.Games != 4 || s.Wins != 2 || s.Draws != 1 || s.Losses != 1 || s.WinRate != 0.625 {
+		t.Fatalf("statistiques %+v", s)
+	}
+}
+
+func TestParseSelfPlayConfigs(t *testing.T) {
+	configs, err := parseSelfPlayConfigs("6x7, 7x8", "normal,rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range configs {
+		got = append(got, c.String())
+	}
+	want := []string{"6x7 normal", "6x7 rotation", "7x8 normal", "7x8 rotation"}
+	if len(got) != len(want) {
+		t.Fatalf("configurations %v, attendu %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("configuration %d: %q, attendu %q", i, got[i], want[i])
+		}
+	}
+	for _, sizes := range []string{"6", "3x7", "6xA", ""} {
+		if _, err := parseSelfPlayConfigs(sizes, "normal"); err == nil {
+			t.Errorf("taille %q acceptée", sizes)
+		}
+	}
+}
+
+func TestPlaySeries(t *testing.T) {
+	configs := []selfPlayConfig{{6, 7, "normal"}, {6, 7, "gauche"}}
+	strong := &AIProfile{Name: "fort", Depth: 3, Weights: defaultWeights}
+	random := &AIProfile{Name: "hasard", Depth: 1, Weights: defaultWeights, MistakeRate: 1}
+	total, byConfig := playSeries(configs, strong, random, 6, 0)
+	if total.Games != 12 {
+		t.Fatalf("%d parties, attendu 12", total.Games)
+	}
+	for _, cfg := range configs {
+		if s := byConfig[cfg.String()]; s == nil || s.Games != 6 {
+			t.Errorf("%s: %+v, attendu 6 parties", cfg, s)
+		}
+	}
+	// Une recherche à 3 demi-coups bat largement un joueur qui tire ses coups au hasard
+	if total.WinRate < 0.75 {
+		t.Errorf("taux de victoire contre le hasard: %.2f", total.WinRate)
+	}
+}
+
+func TestRunSelfPlay(t *testing.T) {
+	saved := dataDir
+	dataDir = t.TempDir()
+	t.Cleanup(func() { dataDir = saved })
+
+	args := []string{"-iterations", "2", "-pairs", "1", "-verify", "2", "-depth", "1", "-sizes", "6x7", "-modes", "normal", "-name", "essai"}
+	if err := runSelfPlay(args); err != nil {
+		t.Fatal(err)
+	}
+	// Sans -out, le profil est écrit dans le répertoire des données
+	data, err := os.ReadFile(dataPath(tunedProfilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var profiles []struct {
+		AIProfile
+		Stats SelfPlayStats `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 {
+		t.Fatalf("%d profils, attendu 1", len(profiles))
+	}
+	p := profiles[0]
+	if p.Name != "essai" || p.Depth != 1 {
+		t.Errorf("profil %+v", p.AIProfile)
+	}
+	if w := p.Weights; w.Two < 0 || w.Three <= w.Two || w.Four <= w.Three {
+		t.Errorf("poids non ordonnés: %+v", w)
+	}
+	if p.Stats.Iterations != 2 || p.Stats.Baseline != defaultWeights || p.Stats.Total.Games != 4 {
+		t.Errorf("statistiques %+v", p.Stats)
+	}
+}
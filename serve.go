@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ServerConfig est la configuration du serveur web. Chaque option se règle par une option de la ligne
+// de commande ou, à défaut, par la variable d'environnement indiquée.
+type ServerConfig struct {
+	Addr              string        // P4_ADDR: adresse d'écoute
+	DataDir           string        // P4_DATA_DIR: fichiers de configuration, résultats et parties enregistrées
+	Dev               bool          // P4_DEV: templates et fichiers statiques lus sur le disque et rechargés à chaque modification
+	WebDir            string        // P4_WEB_DIR: répertoire des templates et fichiers statiques en mode développement
+	BasePath          string        // P4_BASE_PATH: préfixe des adresses, derrière un proxy (ex: /power4)
+	PublicURL         string        // P4_PUBLIC_URL: adresse publique, pour les liens des notifications
+	Presets           string        // P4_PRESETS: plateaux des difficultés (ex: easy=6x7,normal=7x8,hard=8x10+7)
+	AITimeLimit       time.Duration // P4_AI_TIME_LIMIT: temps de réflexion maximal de l'IA par coup (0 = celui du profil)
+	AnalysisTimeLimit time.Duration // P4_ANALYSIS_TIME_LIMIT: temps d'analyse par coup de /analyse
+	LogLevel          string        // P4_LOG_LEVEL: debug, info, warn ou error
+	LogFormat         string        // P4_LOG_FORMAT: json ou text
+	ShutdownTimeout   time.Duration // P4_SHUTDOWN_TIMEOUT: attente maximale des requêtes en cours à l'arrêt
+	AdminToken        string        // P4_ADMIN_TOKEN: jeton de la page /admin (vide: page désactivée)
+	IPRate            float64       // P4_IP_RATE: requêtes par seconde et par adresse IP (0: sans limite)
+	IPBurst           int           // P4_IP_BURST: requêtes d'affilée par adresse IP
+	SessionRate       float64       // P4_SESSION_RATE: requêtes POST par seconde et par visiteur (0: sans limite)
+	SessionBurst      int           // P4_SESSION_BURST: requêtes POST d'affilée par visiteur
+	MaxSearches       int           // P4_MAX_SEARCHES: recherches de l'IA simultanées (0: sans limite)
+	SearchQueue       int           // P4_SEARCH_QUEUE: recherches en attente d'une place, au-delà refusées
+	SearchWait        time.Duration // P4_SEARCH_WAIT: attente maximale d'une place de recherche
+	MaxBody           int64         // P4_MAX_BODY: taille maximale du corps des requêtes, en octets (0: sans limite)
+	TrustedProxies    string        // P4_TRUSTED_PROXIES: proxys dont l'en-tête X-Forwarded-For donne l'adresse du client
+}
+
+// BoardPreset est le plateau d'une difficulté.
+type BoardPreset struct {
+	Rows, Cols, Prefill int
+}
+
+// boardPresets sont les plateaux proposés: facile, normal et difficile.
+var boardPresets = map[string]BoardPreset{
+	"easy":   {6, 7, 0},
+	"normal": {7, 8, 0},
+	"hard":   {8, 10, 7},
+}
+
+var (
+	dataDir     = "."         // répertoire des données (voir dataPath)
+	basePath    = ""          // préfixe des adresses du serveur, sans barre finale
+	aiTimeLimit time.Duration // plafond du temps de réflexion de l'IA (0 = sans plafond)
+	logLevel    = new(slog.LevelVar)
+)
+
+// dataPath retourne le chemin d'un fichier de données dans le répertoire des données.
+func dataPath(name string) string {
+	return filepath.Join(dataDir, name)
+}
+
+// appPath retourne l'adresse d'une page du serveur, préfixe compris.
+func appPath(p string) string {
+	return basePath + p
+}
+
+// envOr retourne la variable d'environnement key, ou def si elle est vide.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envDuration retourne la durée de la variable d'environnement key, ou def si elle est vide.
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// envInt retourne l'entier de la variable d'environnement key, ou def si elle est vide.
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+// envFloat retourne le nombre de la variable d'environnement key, ou def si elle est vide.
+func envFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return f, nil
+}
+
+// parsePresets lit les plateaux des difficultés: "easy=6x7,normal=7x8,hard=8x10+7" (+N cases pré-remplies).
+// Les difficultés absentes gardent leur plateau par défaut.
+func parsePresets(s string) (map[string]BoardPreset, error) {
+	presets := map[string]BoardPreset{}
+	for k, v := range boardPresets {
+		presets[k] = v
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, size, ok := strings.Cut(item, "=")
+		if _, known := presets[name]; !ok || !known {
+			return nil, fmt.Errorf("plateau invalide %q (attendu easy, normal ou hard=LIGNESxCOLONNES[+N])", item)
+		}
+		size, prefillStr, hasPrefill := strings.Cut(size, "+")
+		rowsStr, colsStr, _ := strings.Cut(size, "x")
+		rows, err1 := strconv.Atoi(rowsStr)
+		cols, err2 := strconv.Atoi(colsStr)
+		prefill := 0
+		var err3 error
+		if hasPrefill {
+			prefill, err3 = strconv.Atoi(prefillStr)
+		}
+		if err1 != nil || err2 != nil || err3 != nil || rows < 4 || cols < 4 || rows > 20 || cols > 20 || prefill < 0 || prefill > rows*cols/4 {
+			return nil, fmt.Errorf("plateau invalide %q (4 à 20 lignes et colonnes, au plus un quart de cases pré-remplies)", item)
+		}
+		presets[name] = BoardPreset{rows, cols, prefill}
+	}
+	return presets, nil
+}
+
+// boardLabel retourne la taille affichée du plateau d'une difficulté (ex: "6x7").
+func boardLabel(difficulty string) string {
+	rows, cols, _ := boardSize(difficulty)
+	return fmt.Sprintf("%dx%d", rows, cols)
+}
+
+// templateFuncs sont les fonctions disponibles dans les templates.
+var templateFuncs = template.FuncMap{
+	"path":       appPath,
+	"boardLabel": boardLabel,
+}
+
+// parseLogLevel retourne le niveau de journalisation nommé.
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// parseServerConfig lit les options de serve; les variables d'environnement donnent les valeurs par défaut.
+func parseServerConfig(args []string) (ServerConfig, error) {
+	var cfg ServerConfig
+	aiLimit, err := envDuration("P4_AI_TIME_LIMIT", 0)
+	if err != nil {
+		return cfg, err
+	}
+	analysisLimit, err := envDuration("P4_ANALYSIS_TIME_LIMIT", defaultAnalysisTimeLimit)
+	if err != nil {
+		return cfg, err
+	}
+	shutdownTimeout, err := envDuration("P4_SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return cfg, err
+	}
+	ipRate, err := envFloat("P4_IP_RATE", 20)
+	if err != nil {
+		return cfg, err
+	}
+	ipBurst, err := envInt("P4_IP_BURST", 40)
+	if err != nil {
+		return cfg, err
+	}
+	sessionRate, err := envFloat("P4_SESSION_RATE", 4)
+	if err != nil {
+		return cfg, err
+	}
+	sessionBurst, err := envInt("P4_SESSION_BURST", 8)
+	if err != nil {
+		return cfg, err
+	}
+	maxSearches, err := envInt("P4_MAX_SEARCHES", runtime.NumCPU())
+	if err != nil {
+		return cfg, err
+	}
+	searchQueue, err := envInt("P4_SEARCH_QUEUE", 32)
+	if err != nil {
+		return cfg, err
+	}
+	searchWait, err := envDuration("P4_SEARCH_WAIT", 10*time.Second)
+	if err != nil {
+		return cfg, err
+	}
+	maxBody, err := envInt("P4_MAX_BODY", 64<<10)
+	if err != nil {
+		return cfg, err
+	}
+	fset := flag.NewFlagSet("serve", flag.ExitOnError)
+	fset.StringVar(&cfg.Addr, "addr", envOr("P4_ADDR", ":8081"), "adresse d'écoute (P4_ADDR)")
+	fset.StringVar(&cfg.DataDir, "data", envOr("P4_DATA_DIR", "."), "répertoire des données: configuration, résultats, parties enregistrées (P4_DATA_DIR)")
+	fset.BoolVar(&cfg.Dev, "dev", os.Getenv("P4_DEV") != "", "mode développement: templates et fichiers statiques lus sur le disque, rechargés à chaque modification (P4_DEV)")
+	fset.StringVar(&cfg.WebDir, "web", envOr("P4_WEB_DIR", "."), "répertoire des templates et fichiers statiques en mode développement (P4_WEB_DIR)")
+	fset.StringVar(&cfg.BasePath, "base", envOr("P4_BASE_PATH", ""), "préfixe des adresses, ex: /power4 (P4_BASE_PATH)")
+	fset.StringVar(&cfg.PublicURL, "public-url", envOr("P4_PUBLIC_URL", ""), "adresse publique du serveur, pour les notifications (P4_PUBLIC_URL)")
+	fset.StringVar(&cfg.Presets, "presets", envOr("P4_PRESETS", ""), "plateaux des difficultés, ex: easy=6x7,normal=7x8,hard=8x10+7 (P4_PRESETS)")
+	fset.DurationVar(&cfg.AITimeLimit, "ai-time-limit", aiLimit, "temps de réflexion maximal de l'IA par coup, 0 = celui du profil (P4_AI_TIME_LIMIT)")
+	fset.DurationVar(&cfg.AnalysisTimeLimit, "analysis-time-limit", analysisLimit, "temps d'analyse par coup de /analyse (P4_ANALYSIS_TIME_LIMIT)")
+	fset.StringVar(&cfg.LogLevel, "log-level", envOr("P4_LOG_LEVEL", "info"), "niveau de journalisation: debug, info, warn ou error (P4_LOG_LEVEL)")
+	fset.StringVar(&cfg.LogFormat, "log-format", envOr("P4_LOG_FORMAT", "json"), "format du journal: json ou text (P4_LOG_FORMAT)")
+	fset.StringVar(&cfg.AdminToken, "admin-token", os.Getenv("P4_ADMIN_TOKEN"), "jeton de la page /admin, désactivée sans jeton (P4_ADMIN_TOKEN)")
+	fset.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", shutdownTimeout, "attente maximale des requêtes en cours (recherches de l'IA comprises) à l'arrêt (P4_SHUTDOWN_TIMEOUT)")
+	fset.Float64Var(&cfg.IPRate, "ip-rate", ipRate, "requêtes par seconde et par adresse IP, 0 = sans limite (P4_IP_RATE)")
+	fset.IntVar(&cfg.IPBurst, "ip-burst", ipBurst, "requêtes d'affilée par adresse IP (P4_IP_BURST)")
+	fset.Float64Var(&cfg.SessionRate, "session-rate", sessionRate, "requêtes POST (coups, messages...) par seconde et par visiteur, 0 = sans limite (P4_SESSION_RATE)")
+	fset.IntVar(&cfg.SessionBurst, "session-burst", sessionBurst, "requêtes POST d'affilée par visiteur (P4_SESSION_BURST)")
+	fset.IntVar(&cfg.MaxSearches, "max-searches", maxSearches, "recherches de l'IA simultanées (coups, indices, analyses), 0 = sans limite (P4_MAX_SEARCHES)")
+	fset.IntVar(&cfg.SearchQueue, "search-queue", searchQueue, "recherches en attente d'une place, au-delà refusées par 429 (P4_SEARCH_QUEUE)")
+	fset.DurationVar(&cfg.SearchWait, "search-wait", searchWait, "attente maximale d'une place de recherche avant un refus 429 (P4_SEARCH_WAIT)")
+	fset.Int64Var(&cfg.MaxBody, "max-body", int64(maxBody), "taille maximale du corps des requêtes en octets, 0 = sans limite (P4_MAX_BODY)")
+	fset.StringVar(&cfg.TrustedProxies, "trusted-proxies", os.Getenv("P4_TRUSTED_PROXIES"), "proxys de confiance (IP ou CIDR séparés par des virgules) dont l'en-tête X-Forwarded-For donne l'adresse du client pour -ip-rate (P4_TRUSTED_PROXIES)")
+	fset.Parse(args)
+	if fset.NArg() > 0 {
+		return cfg, fmt.Errorf("argument inattendu: %q", fset.Arg(0))
+	}
+
+	if cfg.BasePath != "" {
+		cfg.BasePath = path.Clean("/" + cfg.BasePath)
+		if cfg.BasePath == "/" {
+			cfg.BasePath = ""
+		}
+		if strings.ContainsAny(cfg.BasePath, "\"'<>?#%\\ ") {
+			return cfg, fmt.Errorf("préfixe invalide: %q", cfg.BasePath)
+		}
+	}
+	if cfg.AITimeLimit < 0 || cfg.AnalysisTimeLimit <= 0 {
+		return cfg, errors.New("les temps de réflexion doivent être positifs")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		return cfg, errors.New("le délai d'arrêt doit être positif")
+	}
+	if cfg.IPRate < 0 || cfg.SessionRate < 0 || cfg.MaxSearches < 0 || cfg.SearchQueue < 0 || cfg.MaxBody < 0 {
+		return cfg, errors.New("les limites doivent être positives ou nulles")
+	}
+	if (cfg.IPRate > 0 && cfg.IPBurst < 1) || (cfg.SessionRate > 0 && cfg.SessionBurst < 1) {
+		return cfg, errors.New("les rafales doivent compter au moins une requête")
+	}
+	if _, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		return cfg, err
+	}
+	if cfg.SearchWait <= 0 {
+		return cfg, errors.New("l'attente d'une place de recherche doit être positive")
+	}
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		return cfg, fmt.Errorf("niveau de journalisation invalide: %q", cfg.LogLevel)
+	}
+	if cfg.LogFormat != "json" && cfg.LogFormat != "text" {
+		return cfg, fmt.Errorf("format de journal invalide: %q", cfg.LogFormat)
+	}
+	if _, err := parsePresets(cfg.Presets); err != nil {
+		return cfg, err
+	}
+	if cfg.PublicURL == "" {
+		host := cfg.Addr
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		cfg.PublicURL = "http://" + host + cfg.BasePath
+	}
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	return cfg, nil
+}
+
+// apply met en place la configuration (valeurs déjà vérifiées par parseServerConfig).
+func (cfg ServerConfig) apply() {
+	dataDir, basePath, baseURL = cfg.DataDir, cfg.BasePath, cfg.PublicURL
+	if cfg.Dev {
+		useWebDir(cfg.WebDir)
+	}
+	aiTimeLimit, analysisTimeLimit = cfg.AITimeLimit, cfg.AnalysisTimeLimit
+	adminToken = cfg.AdminToken
+	ipLimiter = newRateLimiter(cfg.IPRate, cfg.IPBurst)
+	sessionLimiter = newRateLimiter(cfg.SessionRate, cfg.SessionBurst)
+	aiSearches = newSearchLimiter(cfg.MaxSearches, cfg.SearchQueue, cfg.SearchWait)
+	maxBodyBytes = cfg.MaxBody
+	trustedProxies, _ = parseTrustedProxies(cfg.TrustedProxies)
+	boardPresets, _ = parsePresets(cfg.Presets)
+	level, _ := parseLogLevel(cfg.LogLevel)
+	logLevel.Set(level)
+}
+
+// loadConfigFiles charge la bibliothèque d'ouvertures, les profils d'IA et les adversaires externes
+// du répertoire des données.
+func loadConfigFiles() error {
+	var err error
+	if openingBook, err = loadOpeningBook(dataPath(openingBookPath)); err != nil {
+		return fmt.Errorf("bibliothèque d'ouvertures: %w", err)
+	}
+	if err := loadAIProfiles(dataPath(aiProfilesPath)); err != nil {
+		return fmt.Errorf("profils IA: %w", err)
+	}
+	if err := loadEngines(dataPath(enginesPath)); err != nil {
+		return fmt.Errorf("moteurs: %w", err)
+	}
+	if err := loadWebhooks(dataPath(webhooksPath)); err != nil {
+		return fmt.Errorf("bots HTTP: %w", err)
+	}
+	return nil
+}
+
+// routes retourne les pages du serveur, montées sous le préfixe configuré.
+func routes() http.Handler {
+	mux := http.NewServeMux()
+	// Les pages sont comptées et chronométrées dans les métriques, et soumises aux limites du serveur
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, instrument(pattern, limit(h)))
+	}
+	handle("/", startHandler)
+	handle("/mode", modeHandler)
+	handle("/ai-move", aiMoveHandler)
+	handle("/hint", hintHandler)
+	handle("/analyse", analyseHandler)
+	handle("/arena", arenaHandler)
+	handle("/connect4", handler)
+	handle("/lobby", lobbyHandler)
+	handle("/room", roomHandler)
+	handle("/watch", watchHandler)
+	handle("/events", eventsHandler)
+	handle("/chat", chatHandler)
+	handle("/games", myGamesHandler)
+	handle("/api/game", http.NewCrossOriginProtection().Handler(http.HandlerFunc(apiHandler)).ServeHTTP)
+	handle("/admin", adminHandler)
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	// Servez le CSS avec des en-têtes no-cache pour éviter les problèmes de cache navigateur
+	mux.HandleFunc("/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+		http.ServeFileFS(w, r, webAssets, "style.css")
+	})
+	mux.HandleFunc("/favicon.svg", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, webAssets, "favicon.svg")
+	})
+	if basePath == "" {
+		return mux
+	}
+	root := http.NewServeMux()
+	root.Handle(basePath+"/", http.StripPrefix(basePath, mux))
+	root.Handle(basePath, http.RedirectHandler(basePath+"/", http.StatusMovedPermanently))
+	return root
+}
+
+// runServe lance le serveur web.
+func runServe(args []string) error {
+	cfg, err := parseServerConfig(args)
+	if err != nil {
+		return err
+	}
+	cfg.apply()
+	slog.SetDefault(slog.New(newLogHandler(os.Stderr, cfg.LogFormat)))
+
+	if err := loadTemplates(); err != nil {
+		return fmt.Errorf("templates: %w", err)
+	}
+	if err := loadConfigFiles(); err != nil {
+		return err
+	}
+	defer closeEngines()
+	if err := loadNotifiers(dataPath(notifiersPath)); err != nil {
+		return fmt.Errorf("notificateurs: %w", err)
+	}
+	fileStore, err := newFileStore(dataPath(gamesDir))
+	if err != nil {
+		return fmt.Errorf("stockage des parties: %w", err)
+	}
+	store = fileStore
+	restored, err := restoreSessions(store)
+	if err != nil {
+		return fmt.Errorf("parties enregistrées: %w", err)
+	}
+	if restored > 0 {
+		slog.Info("parties rechargées", "count", restored)
+	}
+	runSweeper(sweepInterval)
+
+	if cfg.Dev {
+		slog.Info("mode développement: templates et fichiers statiques lus sur le disque", "dir", cfg.WebDir)
+	}
+	slog.Info("Serveur Puissance 4 Go", "addr", cfg.Addr, "url", cfg.PublicURL+"/", "data", cfg.DataDir)
+	return serve(&http.Server{Addr: cfg.Addr, Handler: routes()}, cfg.ShutdownTimeout)
+}
+
+// serve fait tourner le serveur jusqu'à SIGINT ou SIGTERM, puis l'arrête proprement: plus de nouvelles
+// connexions, attente des requêtes en cours (coups de l'IA compris) et enregistrement des parties en cours,
+// rechargées au prochain démarrage.
+func serve(srv *http.Server, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Les flux d'événements ne se terminent jamais d'eux-mêmes
+	srv.RegisterOnShutdown(closeEvents)
+	srv.RegisterOnShutdown(func() { shuttingDown.Store(true) })
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	slog.Info("arrêt du serveur: attente des requêtes en cours", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("arrêt du serveur: requêtes interrompues", "err", err)
+	}
+	saved, err := snapshotSessions(store)
+	slog.Info("parties enregistrées avant l'arrêt", "count", saved)
+	return err
+}
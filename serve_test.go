@@ -0,0 +1,151 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServerConfigPrecedence(t *testing.T) {
+	t.Setenv("P4_ADDR", "")
+	t.Setenv("P4_IP_RATE", "")
+	t.Setenv("P4_SEARCH_WAIT", "")
+	cfg, err := parseServerConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8081" || cfg.IPRate != 20 || cfg.SearchWait != 10*time.Second {
+		t.Errorf("valeurs par défaut: %q, %g, %v", cfg.Addr, cfg.IPRate, cfg.SearchWait)
+	}
+
+	// L'environnement remplace les valeurs par défaut, les options remplacent l'environnement
+	t.Setenv("P4_ADDR", ":9000")
+	t.Setenv("P4_IP_RATE", "5")
+	t.Setenv("P4_SEARCH_WAIT", "2s")
+	cfg, err = parseServerConfig([]string{"-ip-rate", "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9000" || cfg.IPRate != 7 || cfg.SearchWait != 2*time.Second {
+		t.Errorf("environnement puis options: %q, %g, %v", cfg.Addr, cfg.IPRate, cfg.SearchWait)
+	}
+}
+
+func TestParseServerConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{"durée invalide dans l'environnement", map[string]string{"P4_SEARCH_WAIT": "bientôt"}, nil},
+		{"entier invalide dans l'environnement", map[string]string{"P4_IP_BURST": "beaucoup"}, nil},
+		{"argument en trop", nil, []string{"serve"}},
+		{"préfixe avec espace", nil, []string{"-base", "/mon jeu"}},
+		{"limite négative", nil, []string{"-ip-rate", "-1"}},
+		{"rafale vide", nil, []string{"-session-burst", "0"}},
+		{"niveau de journal inconnu", nil, []string{"-log-level", "bavard"}},
+		{"format de journal inconnu", nil, []string{"-log-format", "xml"}},
+		{"plateau invalide", nil, []string{"-presets", "easy=3x3"}},
+		{"proxy invalide", nil, []string{"-trusted-proxies", "proxy.local"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := parseServerConfig(tt.args); err == nil {
+				t.Error("configuration acceptée")
+			}
+		})
+	}
+}
+
+func TestParseServerConfigBasePath(t *testing.T) {
+	t.Setenv("P4_PUBLIC_URL", "")
+	tests := []struct {
+		base, want, publicURL string
+	}{
+		{"", "", "http://localhost:8081"},
+		{"/", "", "http://localhost:8081"},
+		{"power4", "/power4", "http://localhost:8081/power4"},
+		{"/power4/", "/power4", "http://localhost:8081/power4"},
+		{"//jeux//p4/", "/jeux/p4", "http://localhost:8081/jeux/p4"},
+		{"/a/../p4", "/p4", "http://localhost:8081/p4"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseServerConfig([]string{"-addr", ":8081", "-base", tt.base})
+		if err != nil {
+			t.Errorf("-base %q: %v", tt.base, err)
+			continue
+		}
+		if cfg.BasePath != tt.want || cfg.PublicURL != tt.publicURL {
+			t.Errorf("-base %q: préfixe %q, adresse publique %q; attendu %q, %q", tt.base, cfg.BasePath, cfg.PublicURL, tt.want, tt.publicURL)
+		}
+	}
+	cfg, err := parseServerConfig([]string{"-public-url", "https://jeux.example.org/p4/"})
+	if err != nil || cfg.PublicURL != "https://jeux.example.org/p4" {
+		t.Errorf("adresse publique %q, %v", cfg.PublicURL, err)
+	}
+}
+
+func TestParsePresets(t *testing.T) {
+	presets, err := parsePresets(" normal=8x9, hard=9x12+10 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]BoardPreset{"easy": boardPresets["easy"], "normal": {8, 9, 0}, "hard": {9, 12, 10}}
+	for name, p := range want {
+		if presets[name] != p {
+			t.Errorf("%s: %+v, attendu %+v", name, presets[name], p)
+		}
+	}
+	for _, s := range []string{"expert=6x7", "easy", "easy=6", "easy=6xA", "easy=3x7", "easy=6x21", "hard=8x10+-1", "hard=8x10+21", "hard=8x10+x"} {
+		if _, err := parsePresets(s); err == nil {
+			t.Errorf("plateaux %q acceptés", s)
+		}
+	}
+}
+
+func TestGameFromMoves(t *testing.T) {
+	g, err := gameFromMoves(selfPlayConfig{6, 7, "normal"}, "4, 4,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.History) != 3 || g.Board[5][3] != 1 || g.Board[4][3] != 2 || g.Board[5][2] != 1 {
+		t.Errorf("partie rejouée: %+v", g.History)
+	}
+	for _, moves := range []string{"", "4,x", "8", "1,1,2,2,3,3,4,5"} {
+		if _, err := gameFromMoves(selfPlayConfig{6, 7, "normal"}, moves); err == nil {
+			t.Errorf("coups %q acceptés", moves)
+		}
+	}
+}
+
+func TestRunAnalyseAndBench(t *testing.T) {
+	if err := runAnalyse([]string{"-moves", "4,4,3,3", "-depth", "2", "-time", "50ms", "-json"}); err != nil {
+		t.Errorf("analyse: %v", err)
+	}
+	// Partie enregistrée par le serveur
+	path := filepath.Join(t.TempDir(), "partie.json")
+	st := &fileStore{dir: filepath.Dir(path)}
+	g, _ := gameFromMoves(selfPlayConfig{6, 7, "normal"}, "4,4")
+	if err := st.Save(&GameRecord{ID: strings.TrimSuffix(filepath.Base(path), ".json"), Game: g}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAnalyse([]string{"-game", path, "-depth", "1", "-time", "50ms"}); err != nil {
+		t.Errorf("analyse d'une partie enregistrée: %v", err)
+	}
+	for _, args := range [][]string{{}, {"-moves", "4", "-size", "6"}, {"-game", filepath.Join(t.TempDir(), "absente.json")}} {
+		if err := runAnalyse(args); err == nil {
+			t.Errorf("analyse %v acceptée", args)
+		}
+	}
+
+	if err := runBench([]string{"-positions", "1", "-depth", "2", "-sizes", "6x7", "-modes", "normal"}); err != nil {
+		t.Errorf("bench: %v", err)
+	}
+	if err := runBench([]string{"-sizes", "7"}); err == nil {
+		t.Error("bench avec une taille invalide accepté")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	playerCookie   = "p4_player"
+	defaultRating  = 1500
+	sessionIdleTTL = 6 * time.Hour
+
+	correspondenceTTL = 45 * 24 * time.Hour
+)
+
+// Player est un visiteur, identifié par un cookie.
+type Player struct {
+	ID     string
+	Name   string
+	Email  string // adresse pour les notifications des parties par correspondance (facultative)
+	Rating int
+	Skin   string       // dernier skin choisi
+	Solo   *GameSession // partie locale (contre l'IA ou à deux sur le même écran)
+	CSRF   string       // jeton anti-CSRF des formulaires (voir csrfToken)
+}
+
+// GameSession est une partie indépendante avec son propre verrou: plusieurs parties avancent
+// en parallèle sans se bloquer (une recherche de l'IA ne fige que sa propre partie).
+// Les salles en ligne y ajoutent les places des joueurs.
+type GameSession struct {
+	mu      sync.Mutex
+	ID      string
+	Game    *Game
+	Room    *Room     // nil pour une partie locale
+	Version int       // incrémenté à chaque changement visible, pour rafraîchir les pages abonnées
+	Created time.Time // ouverture de la partie (ou de la salle)
+	Updated time.Time // dernière activité, protégée par le verrou global
+
+	subscribers map[*subscriber]bool // pages ouvertes sur la partie (joueurs et spectateurs)
+
+	// Notifications déjà envoyées (parties par correspondance)
+	notifiedTurn turnKey
+	remindedTurn turnKey
+	notifiedOver *Game
+
+	// Partie déjà comptée dans les métriques (début et fin)
+	countedStart *Game
+	countedOver  *Game
+
+	// État déjà journalisé (voir logEvents)
+	loggedGame  *Game
+	loggedMoves int
+	loggedOver  *Game
+}
+
+// Le verrou global protège les joueurs, l'index des parties et les places des salles;
+// l'état de chaque partie est protégé par le verrou de sa session.
+// Ordre de verrouillage: session puis verrou global.
+var (
+	mutex    sync.Mutex
+	players  = map[string]*Player{}
+	sessions = map[string]*GameSession{}
+)
+
+// newID retourne un identifiant aléatoire, impossible à deviner (les salles privées n'ont pas d'autre protection).
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newSession enregistre une nouvelle partie vide.
+func newSession(room *Room) *GameSession {
+	now := time.Now()
+	s := &GameSession{ID: newID(), Room: room, Created: now, Updated: now}
+	mutex.Lock()
+	sessions[s.ID] = s
+	mutex.Unlock()
+	return s
+}
+
+// touch signale un changement de la partie, journalisé avec la requête de ctx. Appelé avec s.mu verrouillé.
+func (s *GameSession) touch(ctx context.Context) {
+	s.Version++
+	s.broadcast()
+	s.countGame()
+	s.logEvents(ctx)
+	mutex.Lock()
+	s.Updated = time.Now()
+	mutex.Unlock()
+	s.persist()
+	s.correspondenceUpdate()
+}
+
+// lookupSession retourne la partie d'identifiant id, ou nil.
+func lookupSession(id string) *GameSession {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return sessions[id]
+}
+
+// knownPlayer retourne le joueur du cookie de la requête, nil si le cookie est absent ou ne
+// correspond à aucun joueur. Contrairement à currentPlayer, aucun joueur n'est créé.
+func knownPlayer(r *http.Request) *Player {
+	c, err := r.Cookie(playerCookie)
+	if err != nil {
+		return nil
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	return players[c.Value]
+}
+
+// currentPlayer retourne le visiteur associé au cookie de la requête, en le créant au besoin.
+func currentPlayer(w http.ResponseWriter, r *http.Request) *Player {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if c, err := r.Cookie(playerCookie); err == nil {
+		if p, ok := players[c.Value]; ok {
+			return p
+		}
+	}
+	p := &Player{ID: newID(), Rating: defaultRating}
+	players[p.ID] = p
+	http.SetCookie(w, &http.Cookie{
+		Name:     playerCookie,
+		Value:    p.ID,
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return p
+}
+
+// rename change le pseudo du joueur, vérifié par validateName (comme ceux de GameConfig);
+// un joueur sans pseudo devient "Anonyme".
+func (p *Player) rename(name string) error {
+	name = strings.TrimSpace(name)
+	if err := validateName(name); err != nil {
+		return fmt.Errorf("pseudo %w", err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if name != "" {
+		p.Name = name
+	} else if p.Name == "" {
+		p.Name = "Anonyme"
+	}
+	return nil
+}
+
+// setEmail enregistre l'adresse de notification du joueur; une adresse vide ou invalide est ignorée.
+func (p *Player) setEmail(email string) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return
+	}
+	mutex.Lock()
+	p.Email = addr.Address
+	mutex.Unlock()
+}
+
+// solo retourne la partie locale du joueur, en la créant au besoin.
+func (p *Player) solo() *GameSession {
+	mutex.Lock()
+	s := p.Solo
+	mutex.Unlock()
+	if s != nil {
+		return s
+	}
+	s = newSession(nil)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if p.Solo == nil {
+		p.Solo = s
+	}
+	return p.Solo
+}
+
+// requestSession retourne la partie désignée par le paramètre id, ou à défaut la partie locale du visiteur.
+func requestSession(w http.ResponseWriter, r *http.Request) *GameSession {
+	if id := r.URL.Query().Get("id"); id != "" {
+		return lookupSession(id)
+	}
+	return currentPlayer(w, r).solo()
+}
+
+// pruneSessions oublie les parties inactives depuis plus de sessionIdleTTL.
+// Les parties par correspondance sont conservées plus longtemps que leur délai maximal par coup:
+// une partie inactive depuis correspondenceTTL est forcément terminée.
+func pruneSessions() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for id, s := range sessions {
+		ttl := sessionIdleTTL
+		if s.persistent() {
+			ttl = correspondenceTTL
+		}
+		if time.Since(s.Updated) < ttl {
+			continue
+		}
+		if s.persistent() && store != nil {
+			store.Delete(id)
+		}
+		delete(sessions, id)
+		for _, p := range players {
+			if p.Solo == s {
+				p.Solo = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	eventsTick      = time.Second      // vérification de la pendule des parties suivies
+	eventsKeepAlive = 15 * time.Second // commentaire envoyé pour garder la connexion ouverte
+)
+
+// subscriber est une page abonnée aux changements d'une partie.
+type subscriber struct {
+	notify    chan struct{}
+	player    string
+	spectator bool
+	chatSeen  int // numéro du dernier message de son canal déjà transmis
+}
+
+// seated indique si le joueur participe à la partie (place dans la salle, ou propriétaire de la partie locale).
+// Appelé avec s.mu verrouillé.
+func (s *GameSession) seated(p *Player) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if s.Room != nil {
+		return s.Room.seatOf(p) != 0
+	}
+	return p.Solo == s
+}
+
+// spectatorCount retourne le nombre de visiteurs distincts qui regardent la partie. Appelé avec s.mu verrouillé.
+func (s *GameSession) spectatorCount() int {
+	seen := map[string]bool{}
+	for sub := range s.subscribers {
+		if sub.spectator {
+			seen[sub.player] = true
+		}
+	}
+	return len(seen)
+}
+
+// broadcast prévient les pages abonnées sans jamais bloquer. Appelé avec s.mu verrouillé.
+func (s *GameSession) broadcast() {
+	for sub := range s.subscribers {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribe abonne une page aux changements de la partie. Appelé avec s.mu verrouillé.
+func (s *GameSession) subscribe(sub *subscriber) {
+	if s.subscribers == nil {
+		s.subscribers = map[*subscriber]bool{}
+	}
+	s.subscribers[sub] = true
+	s.broadcast()
+}
+
+// unsubscribe retire une page abonnée. Appelé avec s.mu verrouillé.
+func (s *GameSession) unsubscribe(sub *subscriber) {
+	delete(s.subscribers, sub)
+	s.broadcast()
+}
+
+// shutdownEvents est fermé à l'arrêt du serveur pour terminer les flux d'événements.
+var (
+	shutdownEvents  = make(chan struct{})
+	closeEventsOnce sync.Once
+)
+
+// closeEvents termine tous les flux d'événements ouverts.
+func closeEvents() {
+	closeEventsOnce.Do(func() { close(shutdownEvents) })
+}
+
+// eventsHandler diffuse l'état de la partie en Server-Sent Events: la version (la page se recharge
+// quand elle change), le nombre de spectateurs et les nouveaux messages du canal de discussion du visiteur.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	sess := lookupSession(r.URL.Query().Get("id"))
+	flusher, ok := w.(http.Flusher)
+	if sess == nil || !ok {
+		http.NotFound(w, r)
+		return
+	}
+	player := currentPlayer(w, r)
+	sub := &subscriber{notify: make(chan struct{}, 1), player: player.ID}
+	// La page reçoit à l'affichage les messages déjà échangés
+	sub.chatSeen, _ = strconv.Atoi(r.URL.Query().Get("chat"))
+	sess.mu.Lock()
+	sub.spectator = !sess.seated(player)
+	sess.subscribe(sub)
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		sess.unsubscribe(sub)
+		sess.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	tick := time.NewTicker(eventsTick)
+	defer tick.Stop()
+	lastWrite := time.Now()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-shutdownEvents:
+			return
+		case <-sub.notify:
+		case <-tick.C:
+			// La pendule n'avance côté serveur qu'à la demande: on vérifie ici la perte au temps
+			sess.mu.Lock()
+			if sess.Game != nil && sess.Game.checkTimeout() {
+				sess.touch(r.Context())
+				sess.settle()
+			}
+			sess.mu.Unlock()
+			if time.Since(lastWrite) >= eventsKeepAlive {
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+				lastWrite = time.Now()
+			}
+			continue
+		}
+		sess.mu.Lock()
+		var chat []ChatMessage
+		if sess.Game != nil {
+			channel := chatPlayers
+			if sub.spectator {
+				channel = chatSpectators
+			}
+			if sub.chatSeen > sess.Game.ChatSeq[channel] {
+				sub.chatSeen = 0 // nouvelle partie
+			}
+			for _, msg := range sess.Game.Chat[channel] {
+				if msg.ID > sub.chatSeen {
+					chat = append(chat, msg)
+				}
+			}
+			sub.chatSeen = sess.Game.ChatSeq[channel]
+		}
+		state, _ := json.Marshal(struct {
+			Version    int `json:"version"`
+			Spectators int `json:"spectators"`
+		}{sess.Version, sess.spectatorCount()})
+		sess.mu.Unlock()
+		for _, msg := range chat {
+			data, _ := json.Marshal(msg)
+			fmt.Fprintf(w, "event: chat\ndata: %s\n\n", data)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", state)
+		flusher.Flush()
+		lastWrite = time.Now()
+	}
+}
+
+// watchHandler affiche une partie en lecture seule: le même plateau, sans interaction.
+func watchHandler(w http.ResponseWriter, r *http.Request) {
+	sess := lookupSession(r.URL.Query().Get("id"))
+	if sess == nil {
+		http.Error(w, "Partie introuvable", http.StatusNotFound)
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	game := sess.Game
+	if game == nil {
+		http.Error(w, "Aucune partie en cours", http.StatusNotFound)
+		return
+	}
+	if game.checkTimeout() {
+		sess.touch(r.Context())
+		sess.settle()
+	}
+
+	data := newGameView(game, 0)
+	data.AnalyseURL = appPath("/analyse?id=" + sess.ID)
+	data.BoardHTML = renderBoard(game, boardView{AnalyseURL: data.AnalyseURL})
+	data.Spectator = true
+	sess.describe(&data, currentPlayer(w, r))
+	pageTmpl.Execute(w, data)
+}
+
+// describe complète les données de la page avec l'état en ligne de la partie, vue par le visiteur.
+// Appelé avec s.mu verrouillé.
+func (s *GameSession) describe(data *gameView, viewer *Player) {
+	data.SessionID = s.ID
+	data.Version = s.Version
+	data.Spectators = s.spectatorCount()
+	data.ChatChannel = s.chatChannel(viewer)
+	data.ChatEnabled = s.Room != nil || data.ChatChannel == chatSpectators
+	if s.Game != nil {
+		data.Chat = s.Game.Chat[data.ChatChannel]
+		data.ChatLast = s.Game.ChatSeq[data.ChatChannel]
+	}
+	data.Reactions = reactions
+	data.ChatMax = chatMaxLength
+	data.CSRF = viewer.csrfToken()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if viewer.Skin != "" {
+		data.Skin = viewer.Skin
+	}
+	if room := s.Room; room != nil {
+		data.RoomID = s.ID
+		data.Waiting = room.waiting()
+		data.Rating1 = room.Seats[0].Rating
+		if !room.waiting() {
+			data.Rating2 = room.Seats[1].Rating
+		}
+	}
+}
+
+// LiveEntry est une partie en ligne en cours, que l'on peut regarder depuis le salon.
+type LiveEntry struct {
+	ID         string
+	Player1    string
+	Player2    string
+	Moves      int
+	Spectators int
+}
+
+// liveRooms retourne les salles publiques dont la partie est en cours.
+func liveRooms() []LiveEntry {
+	mutex.Lock()
+	var list []*GameSession
+	for _, s := range sessions {
+		if s.Room != nil && s.Room.Public && !s.Room.waiting() {
+			list = append(list, s)
+		}
+	}
+	mutex.Unlock()
+
+	var entries []LiveEntry
+	for _, s := range list {
+		s.mu.Lock()
+		if g := s.Game; g != nil && !g.GameOver {
+			entries = append(entries, LiveEntry{
+				ID:         s.ID,
+				Player1:    g.Username1,
+				Player2:    g.Username2,
+				Moves:      len(g.History),
+				Spectators: s.spectatorCount(),
+			})
+		}
+		s.mu.Unlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Spectators != entries[j].Spectators {
+			return entries[i].Spectators > entries[j].Spectators
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// absoluteURL retourne l'adresse complète d'un chemin du serveur (préfixe compris), pour les liens à partager.
+func absoluteURL(r *http.Request, path string) string {
+	scheme := "http://"
+	if r.TLS != nil {
+		scheme = "https://"
+	}
+	return scheme + r.Host + appPath(path)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent est un événement reçu d'un flux /events.
+type sseEvent struct {
+	Name string // vide pour l'état de la partie
+	Data string
+}
+
+// openEvents ouvre le flux d'événements de la partie au nom du joueur p; chat est le numéro
+// du dernier message déjà affiché. Le flux est fermé à la fin du test.
+func openEvents(t *testing.T, sess *GameSession, p *Player, chat int) <-chan sseEvent {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(eventsHandler))
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s?id=%s&chat=%d", srv.URL, sess.ID, chat), nil)
+	req.AddCookie(&http.Cookie{Name: playerCookie, Value: p.ID})
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		res.Body.Close()
+		srv.Close()
+	})
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		sc := bufio.NewScanner(res.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.Name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.Data = strings.TrimPrefix(line, "data: ")
+			case line == "" && ev.Data != "":
+				events <- ev
+				ev = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// liveState attend le prochain état de la partie du flux, en sautant les messages de discussion.
+func liveState(t *testing.T, events <-chan sseEvent) (version, spectators int) {
+	t.Helper()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("flux fermé")
+			}
+			if ev.Name != "" {
+				continue
+			}
+			var st struct {
+				Version    int `json:"version"`
+				Spectators int `json:"spectators"`
+			}
+			if err := json.Unmarshal([]byte(ev.Data), &st); err != nil {
+				t.Fatal(err)
+			}
+			return st.Version, st.Spectators
+		case <-time.After(2 * time.Second):
+			t.Fatal("aucun état reçu")
+		}
+	}
+}
+
+// newTestPlayer enregistre un visiteur le temps du test.
+func newTestPlayer(t *testing.T, name string) *Player {
+	p := &Player{ID: newID(), Name: name, Rating: defaultRating}
+	mutex.Lock()
+	players[p.ID] = p
+	mutex.Unlock()
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(players, p.ID)
+		mutex.Unlock()
+	})
+	return p
+}
+
+func TestEventsSpectators(t *testing.T) {
+	sess := newSession(nil)
+	sess.Game = newTestGame("normal")
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, sess.ID)
+		mutex.Unlock()
+	})
+	owner := newTestPlayer(t, "Alice")
+	owner.Solo = sess
+	watcher := newTestPlayer(t, "Zoé")
+
+	// Le propriétaire de la partie n'est pas compté parmi les spectateurs
+	ownerEvents := openEvents(t, sess, owner, 0)
+	if _, n := liveState(t, ownerEvents); n != 0 {
+		t.Fatalf("%d spectateurs, attendu 0", n)
+	}
+	watcherEvents := openEvents(t, sess, watcher, 0)
+	if _, n := liveState(t, watcherEvents); n != 1 {
+		t.Fatalf("spectateur: %d spectateurs, attendu 1", n)
+	}
+	if _, n := liveState(t, ownerEvents); n != 1 {
+		t.Fatalf("propriétaire: %d spectateurs, attendu 1", n)
+	}
+
+	// Un coup change la version: les deux pages sont prévenues
+	sess.mu.Lock()
+	sess.Game.DropToken(3)
+	sess.touch(t.Context())
+	version := sess.Version
+	sess.mu.Unlock()
+	for _, events := range []<-chan sseEvent{ownerEvents, watcherEvents} {
+		if v, _ := liveState(t, events); v != version {
+			t.Errorf("version %d, attendu %d", v, version)
+		}
+	}
+}
+
+func TestLiveRooms(t *testing.T) {
+	newRoom := func(public, full bool, spectators int) *GameSession {
+		room := &Room{RoomSettings: RoomSettings{Difficulty: "easy", Mode: "normal", BestOf: 1}, Public: public, Created: time.Now()}
+		room.Seats[0] = &Player{ID: newID(), Name: "Alice"}
+		if full {
+			room.Seats[1] = &Player{ID: newID(), Name: "Bob"}
+		}
+		sess := newSession(room)
+		sess.Game = newRoomGame(room, nil)
+		for i := range spectators {
+			sess.subscribe(&subscriber{notify: make(chan struct{}, 1), player: fmt.Sprint(i), spectator: true})
+		}
+		t.Cleanup(func() {
+			mutex.Lock()
+			delete(sessions, sess.ID)
+			mutex.Unlock()
+		})
+		return sess
+	}
+	quiet := newRoom(true, true, 0)
+	popular := newRoom(true, true, 3)
+	newRoom(false, true, 5) // privée
+	newRoom(true, false, 0) // en attente d'un adversaire
+	over := newRoom(true, true, 2)
+	over.Game.finish(1)
+
+	var ids []string
+	for _, e := range liveRooms() {
+		ids = append(ids, e.ID)
+	}
+	want := []string{popular.ID, quiet.ID}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("parties en direct %v, attendu %v", ids, want)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gamesDir est le répertoire où sont enregistrées les parties persistantes.
+const gamesDir = "games"
+
+// PlayerRecord est l'identité d'un joueur enregistrée avec ses parties.
+type PlayerRecord struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Rating int    `json:"rating"`
+	Skin   string `json:"skin,omitempty"`
+}
+
+// RoomRecord est l'état enregistré d'une salle.
+type RoomRecord struct {
+	RoomSettings
+	Public  bool             `json:"public"`
+	Quick   bool             `json:"quick"`
+	Seats   [2]*PlayerRecord `json:"seats"`
+	Created time.Time        `json:"created"`
+	Rated   bool             `json:"rated"`
+}
+
+// GameRecord est l'état enregistré d'une partie.
+type GameRecord struct {
+	ID       string        `json:"id"`
+	Room     *RoomRecord   `json:"room,omitempty"`
+	Owner    *PlayerRecord `json:"owner,omitempty"`    // joueur d'une partie locale
+	Opponent string        `json:"opponent,omitempty"` // moteur externe ou bot HTTP jouant l'IA
+	Game     *Game         `json:"game"`
+	Updated  time.Time     `json:"updated"`
+	SavedAt  time.Time     `json:"saved_at"`
+	Snapshot bool          `json:"snapshot,omitempty"` // sauvegarde d'arrêt, supprimée une fois rechargée
+}
+
+// GameStore enregistre les parties qui doivent survivre à un redémarrage.
+type GameStore interface {
+	Save(rec *GameRecord) error
+	Delete(id string) error
+	LoadAll() ([]*GameRecord, error)
+	Ping() error // vérifie que le stockage est accessible en écriture
+}
+
+// fileStore enregistre chaque partie dans un fichier JSON <id>.json.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(dir string) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (st *fileStore) path(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+// Save écrit la partie dans un fichier temporaire puis le renomme, pour ne jamais laisser de fichier tronqué.
+func (st *fileStore) Save(rec *GameRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.path(rec.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path(rec.ID))
+}
+
+// Ping crée puis supprime un fichier témoin dans le répertoire des parties.
+func (st *fileStore) Ping() error {
+	probe := filepath.Join(st.dir, ".ping")
+	if err := os.WriteFile(probe, nil, 0o644); err != nil {
+		return err
+	}
+	return os.Remove(probe)
+}
+
+func (st *fileStore) Delete(id string) error {
+	err := os.Remove(st.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (st *fileStore) LoadAll() ([]*GameRecord, error) {
+	entries, err := os.ReadDir(st.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []*GameRecord
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(st.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var rec GameRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		if rec.ID == "" || rec.Game == nil {
+			return nil, fmt.Errorf("%s: partie incomplète", e.Name())
+		}
+		records = append(records, &rec)
+	}
+	return records, nil
+}
+
+// store est le stockage des parties (nil: aucune persistance).
+var store GameStore
+
+// playerRecord retourne l'identité enregistrable d'un joueur. Appelé avec le verrou global.
+func playerRecord(p *Player) *PlayerRecord {
+	if p == nil {
+		return nil
+	}
+	return &PlayerRecord{ID: p.ID, Name: p.Name, Email: p.Email, Rating: p.Rating, Skin: p.Skin}
+}
+
+// record retourne l'état enregistrable de la partie. Appelé avec s.mu verrouillé.
+func (s *GameSession) record() *GameRecord {
+	mutex.Lock()
+	defer mutex.Unlock()
+	rec := &GameRecord{ID: s.ID, Game: s.Game, Updated: s.Updated, SavedAt: time.Now()}
+	if s.Game != nil && s.Game.Opponent != nil {
+		rec.Opponent = s.Game.Opponent.Name()
+	}
+	if s.Room == nil {
+		for _, p := range players {
+			if p.Solo == s {
+				rec.Owner = playerRecord(p)
+			}
+		}
+	}
+	if room := s.Room; room != nil {
+		rec.Room = &RoomRecord{
+			RoomSettings: room.RoomSettings,
+			Public:       room.Public,
+			Quick:        room.Quick,
+			Seats:        [2]*PlayerRecord{playerRecord(room.Seats[0]), playerRecord(room.Seats[1])},
+			Created:      room.Created,
+			Rated:        room.Rated,
+		}
+	}
+	return rec
+}
+
+// persist enregistre la partie si elle doit survivre à un redémarrage. Appelé avec s.mu verrouillé.
+func (s *GameSession) persist() {
+	if store == nil || !s.persistent() {
+		return
+	}
+	// Une partie supprimée (salle fermée, administration) n'est plus enregistrée
+	if lookupSession(s.ID) != s {
+		return
+	}
+	if err := store.Save(s.record()); err != nil {
+		slog.Error("enregistrement de la partie", "game", s.ID, "err", err)
+	}
+}
+
+// persistent indique si la partie est enregistrée: seules les parties par correspondance le sont.
+func (s *GameSession) persistent() bool {
+	return s.Room != nil && parseTimeControl(s.Room.Cadence).Correspondence
+}
+
+// restorePlayer retrouve ou recrée le joueur d'une partie enregistrée. Appelé avec le verrou global.
+func restorePlayer(rec *PlayerRecord) *Player {
+	if rec == nil {
+		return nil
+	}
+	if p, ok := players[rec.ID]; ok {
+		return p
+	}
+	p := &Player{ID: rec.ID, Name: rec.Name, Email: rec.Email, Rating: rec.Rating, Skin: rec.Skin}
+	players[p.ID] = p
+	return p
+}
+
+// snapshotSessions enregistre toutes les parties en cours, avant l'arrêt du serveur.
+// Chaque partie est verrouillée le temps de son enregistrement: un coup en cours se termine d'abord.
+func snapshotSessions(st GameStore) (int, error) {
+	mutex.Lock()
+	list := make([]*GameSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	mutex.Unlock()
+
+	saved := 0
+	var errs []error
+	for _, s := range list {
+		s.mu.Lock()
+		if s.Game != nil {
+			rec := s.record()
+			rec.Snapshot = !s.persistent()
+			if err := st.Save(rec); err != nil {
+				errs = append(errs, fmt.Errorf("partie %s: %w", s.ID, err))
+			} else {
+				saved++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return saved, errors.Join(errs...)
+}
+
+// restoreGame relie une partie rechargée à la configuration du serveur: profil d'IA et adversaire externe.
+// La pendule d'une partie sauvegardée à l'arrêt ne tourne pas pendant l'interruption.
+func restoreGame(rec *GameRecord) {
+	g := rec.Game
+	if g.Profile != nil {
+		if p, ok := aiProfiles[g.Profile.Name]; ok {
+			g.Profile = p
+		}
+	}
+	if rec.Opponent != "" {
+		g.Opponent = externalBot(rec.Opponent)
+	}
+	if rec.Snapshot && g.TimeControl.Enabled() && !g.GameOver && !rec.SavedAt.IsZero() {
+		g.TurnStart = g.TurnStart.Add(time.Since(rec.SavedAt))
+	}
+	g.assignSeatTokens()
+}
+
+// restoreSessions recharge les parties enregistrées au démarrage: parties par correspondance et
+// parties sauvegardées à l'arrêt précédent (dont le fichier est alors supprimé).
+func restoreSessions(st GameStore) (int, error) {
+	records, err := st.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	restored := 0
+	for _, rec := range records {
+		// Une sauvegarde d'arrêt ne sert qu'une fois, même si la partie n'est pas reprise
+		if rec.Snapshot {
+			if err := st.Delete(rec.ID); err != nil {
+				return restored, err
+			}
+		}
+		restoreGame(rec)
+		s := &GameSession{ID: rec.ID, Game: rec.Game, Created: rec.Updated, Updated: rec.Updated}
+		if rr := rec.Room; rr != nil {
+			s.Room = &Room{
+				RoomSettings: rr.RoomSettings,
+				Public:       rr.Public,
+				Quick:        rr.Quick,
+				Seats:        [2]*Player{restorePlayer(rr.Seats[0]), restorePlayer(rr.Seats[1])},
+				Created:      rr.Created,
+				Rated:        rr.Rated,
+			}
+			if s.Room.Seats[0] == nil {
+				slog.Warn("partie enregistrée sans hôte ignorée", "game", rec.ID)
+				continue
+			}
+			s.Created = rr.Created
+		} else if owner := restorePlayer(rec.Owner); owner != nil {
+			owner.Solo = s
+		} else {
+			slog.Warn("partie enregistrée sans joueur ignorée", "game", rec.ID)
+			continue
+		}
+		// Les notifications déjà envoyées ne sont pas rejouées
+		s.notifiedTurn = s.turn()
+		s.countedStart, s.loggedGame, s.loggedMoves = s.Game, s.Game, len(s.Game.History)
+		if s.Game.GameOver {
+			s.notifiedOver, s.countedOver, s.loggedOver = s.Game, s.Game, s.Game
+		}
+		sessions[s.ID] = s
+		restored++
+	}
+	return restored, nil
+}
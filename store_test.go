@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	st, err := newFileStore(filepath.Join(t.TempDir(), gamesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	g := newTestGame("rotation")
+	g.SetTimeControl(parseTimeControl("jours-3"))
+	for _, slot := range []int{3, 3, 4} {
+		g.DropToken(slot)
+	}
+	g.Chat[chatPlayers] = append(g.Chat[chatPlayers], ChatMessage{Author: "Alice", Seat: 1, Text: "bonjour"})
+	host := &PlayerRecord{ID: "p1", Name: "Alice", Email: "alice@example.org", Rating: 1234}
+	rec := &GameRecord{
+		ID: "abc123",
+		Room: &RoomRecord{
+			RoomSettings: RoomSettings{Difficulty: "easy", Mode: "rotation", Cadence: "jours-3", BestOf: 3},
+			Seats:        [2]*PlayerRecord{host, {ID: "p2", Name: "Bob", Rating: 1180}},
+			Created:      time.Now().Add(-time.Hour).Round(0),
+		},
+		Game:    g,
+		Updated: time.Now().Round(0),
+	}
+	if err := st.Save(rec); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	records, err := st.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("LoadAll: %d parties, attendu 1", len(records))
+	}
+	got := records[0]
+	if got.ID != rec.ID || !got.Updated.Equal(rec.Updated) {
+		t.Errorf("partie rechargée: id %q, mise à jour %v", got.ID, got.Updated)
+	}
+	if got.Room == nil || got.Room.RoomSettings != rec.Room.RoomSettings || *got.Room.Seats[0] != *host || got.Room.Seats[1].Name != "Bob" {
+		t.Errorf("salle rechargée: %+v", got.Room)
+	}
+	lg := got.Game
+	for r := range g.Board {
+		if !slices.Equal(lg.Board[r], g.Board[r]) {
+			t.Fatalf("ligne %d: %v, attendu %v", r, lg.Board[r], g.Board[r])
+		}
+	}
+	if !slices.Equal(lg.History, g.History) || lg.CurrentPlayer != g.CurrentPlayer || lg.Gravity != g.Gravity || lg.TurnCount != g.TurnCount {
+		t.Errorf("état de la partie différent après rechargement")
+	}
+	if lg.TimeControl != g.TimeControl || lg.Remaining != g.Remaining {
+		t.Errorf("pendule: %+v %v, attendu %+v %v", lg.TimeControl, lg.Remaining, g.TimeControl, g.Remaining)
+	}
+	if lg.SeatTokens != g.SeatTokens {
+		t.Errorf("jetons des places: %v, attendu %v", lg.SeatTokens, g.SeatTokens)
+	}
+	if len(lg.Chat[chatPlayers]) != 1 || lg.Chat[chatPlayers][0].Text != "bonjour" {
+		t.Errorf("discussion: %+v", lg.Chat[chatPlayers])
+	}
+
+	if err := st.Delete(rec.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := st.Delete(rec.ID); err != nil {
+		t.Fatalf("Delete d'une partie absente: %v", err)
+	}
+	if records, err := st.LoadAll(); err != nil || len(records) != 0 {
+		t.Fatalf("LoadAll après Delete: %d parties, %v", len(records), err)
+	}
+}
+
+func TestFileStoreRejectsIncompleteRecord(t *testing.T) {
+	dir := t.TempDir()
+	st, err := newFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"id": "broken"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.LoadAll(); err == nil {
+		t.Fatal("LoadAll accepte une partie sans plateau")
+	}
+}
+
+func TestSnapshotAndRestoreSessions(t *testing.T) {
+	st, err := newFileStore(filepath.Join(t.TempDir(), gamesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	solo := newSession(nil)
+	solo.Game = newTestGame("normal")
+	solo.Game.DropToken(3)
+	owner := &Player{ID: newID(), Name: "Zoé", Rating: defaultRating, Solo: solo}
+	mutex.Lock()
+	players[owner.ID] = owner
+	mutex.Unlock()
+	corr := newCorrespondenceSession(t)
+	host := corr.Room.Seats[0]
+	t.Cleanup(func() {
+		mutex.Lock()
+		delete(sessions, solo.ID)
+		delete(players, owner.ID)
+		delete(players, host.ID)
+		delete(players, corr.Room.Seats[1].ID)
+		mutex.Unlock()
+	})
+	// Sauvegarde d'arrêt d'une salle sans hôte, qui ne peut pas être reprise
+	stale := &GameRecord{ID: "orpheline", Room: &RoomRecord{RoomSettings: RoomSettings{Difficulty: "easy", Mode: "normal", BestOf: 1}},
+		Game: newTestGame("normal"), Snapshot: true}
+	if err := st.Save(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := snapshotSessions(st); err != nil {
+		t.Fatalf("snapshotSessions: %v", err)
+	}
+	// Redémarrage: les parties et les joueurs en mémoire sont perdus
+	mutex.Lock()
+	delete(sessions, solo.ID)
+	delete(sessions, corr.ID)
+	delete(players, owner.ID)
+	delete(players, host.ID)
+	mutex.Unlock()
+
+	if _, err := restoreSessions(st); err != nil {
+		t.Fatalf("restoreSessions: %v", err)
+	}
+	restored := lookupSession(solo.ID)
+	if restored == nil || lookupSession(corr.ID) == nil {
+		t.Fatal("partie non rechargée")
+	}
+	if lookupSession(stale.ID) != nil {
+		t.Error("salle sans hôte rechargée")
+	}
+	mutex.Lock()
+	p := players[owner.ID]
+	mutex.Unlock()
+	if p == nil || p.Name != "Zoé" || p.Solo != restored {
+		t.Fatalf("joueur rechargé: %+v", p)
+	}
+	if !slices.Equal(restored.Game.History, solo.Game.History) {
+		t.Errorf("coups rechargés: %v, attendu %v", restored.Game.History, solo.Game.History)
+	}
+
+	// Les sauvegardes d'arrêt sont supprimées, reprises ou non; la partie par correspondance reste
+	records, err := st.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	if !slices.Equal(ids, []string{corr.ID}) {
+		t.Errorf("parties encore enregistrées: %v, attendu %v", ids, []string{corr.ID})
+	}
+}
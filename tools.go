@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadGameFile lit une partie enregistrée: fichier du répertoire games/ ou état de partie seul.
+func loadGameFile(path string) (*Game, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec GameRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if rec.Game != nil {
+		return rec.Game, nil
+	}
+	var g Game
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if g.Rows == 0 || len(g.InitialBoard) == 0 {
+		return nil, fmt.Errorf("%s: aucune partie trouvée", path)
+	}
+	return &g, nil
+}
+
+// gameFromMoves rejoue une suite de coups (emplacements numérotés à partir de 1) sur un plateau vide.
+func gameFromMoves(cfg selfPlayConfig, moves string) (*Game, error) {
+	g := NewGame(cfg.Rows, cfg.Cols, 0, "", "Joueur 1", "Joueur 2", normalizeMode(cfg.Mode), "", ModeHumanVsHuman, AIEasy)
+	for i, m := range strings.Split(moves, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		slot, err := strconv.Atoi(m)
+		if err != nil || g.GameOver || !g.DropToken(slot-1) {
+			return nil, fmt.Errorf("coup %d invalide: %q", i+1, m)
+		}
+	}
+	if len(g.History) == 0 {
+		return nil, errors.New("aucun coup à analyser")
+	}
+	return g, nil
+}
+
+// runAnalyse analyse une partie comme la page /analyse et affiche le rapport dans le terminal.
+// Usage: power4 analyse (-game fichier.json | -moves 4,4,3,... [-size 6x7] [-mode normal]) [-depth N] [-time 600ms] [-json]
+func runAnalyse(args []string) error {
+	fset := flag.NewFlagSet("analyse", flag.ExitOnError)
+	file := fset.String("game", "", "partie enregistrée (games/<id>.json)")
+	moves := fset.String("moves", "", "coups joués, numérotés à partir de 1 (colonnes, ou lignes en gravité latérale)")
+	size := fset.String("size", "6x7", "taille du plateau pour -moves (lignes x colonnes)")
+	mode := fset.String("mode", "normal", "mode de gravité pour -moves")
+	depth := fset.Int("depth", analysisMaxDepth, "profondeur maximale de recherche")
+	limit := fset.Duration("time", defaultAnalysisTimeLimit, "temps d'analyse par coup")
+	asJSON := fset.Bool("json", false, "rapport au format JSON")
+	fset.Parse(args)
+
+	var g *Game
+	var err error
+	switch {
+	case *file != "":
+		g, err = loadGameFile(*file)
+	case *moves != "":
+		var configs []selfPlayConfig
+		if configs, err = parseSelfPlayConfigs(*size, *mode); err == nil {
+			g, err = gameFromMoves(configs[0], *moves)
+		}
+	default:
+		err = errors.New("indiquez une partie (-game) ou une suite de coups (-moves)")
+	}
+	if err != nil {
+		return err
+	}
+
+	report := analyseGame(g, max(*depth, 1), *limit)
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("%s contre %s, plateau %dx%d, %s, %d coups\n", g.Username1, g.Username2, g.Rows, g.Cols, g.Mode, len(g.History))
+	for _, mr := range report.Moves {
+		axis := "col"
+		if mr.Horizontal {
+			axis = "lig"
+		}
+		note := ""
+		switch {
+		case mr.Blunder:
+			note = "?? gaffe"
+		case mr.MissedWin:
+			note = "?! gain manqué"
+		}
+		fmt.Printf("%3d. %-16s %s %-3d meilleur %-3d (%s, joué %s, perte %d) %s\n",
+			mr.Number, mr.Name, axis, mr.Move.Slot+1, mr.Best+1, mr.BestVerdict, mr.PlayedVerdict, mr.Loss, note)
+	}
+	for p := 0; p < 2; p++ {
+		fmt.Printf("%s: %d gaffe(s), %d gain(s) manqué(s)\n", g.playerName(p+1), report.Blunders[p], report.Missed[p])
+	}
+	return nil
+}
+
+// runBench mesure la vitesse de la recherche sur des positions tirées au hasard.
+// Usage: power4 bench [-positions N] [-depth N] [-sizes 6x7,7x8] [-modes normal] [-random-plies N] [-seed N]
+func runBench(args []string) error {
+	fset := flag.NewFlagSet("bench", flag.ExitOnError)
+	positions := fset.Int("positions", 20, "positions analysées par configuration")
+	depth := fset.Int("depth", 7, "profondeur de recherche")
+	sizes := fset.String("sizes", "6x7,7x8", "tailles de plateau (lignes x colonnes)")
+	modes := fset.String("modes", "normal", "modes de gravité")
+	openingPlies := fset.Int("random-plies", 6, "demi-coups joués au hasard avant chaque recherche")
+	seed := fset.Int64("seed", 1, "graine du tirage des positions (reproductible)")
+	fset.Parse(args)
+
+	configs, err := parseSelfPlayConfigs(*sizes, *modes)
+	if err != nil {
+		return err
+	}
+	*positions = max(*positions, 1)
+	rng := rand.New(rand.NewSource(*seed))
+	var totalNodes int64
+	var totalTime time.Duration
+	for _, cfg := range configs {
+		var nodes int64
+		var elapsed time.Duration
+		searched := 0
+		for searched < *positions {
+			g := NewGame(cfg.Rows, cfg.Cols, 0, "", "", "", normalizeMode(cfg.Mode), "", ModeHumanVsHuman, AIEasy)
+			for g.TurnCount < *openingPlies && !g.GameOver {
+				moves := g.getValidMoves()
+				g.DropToken(moves[rng.Intn(len(moves))])
+			}
+			if g.GameOver {
+				continue
+			}
+			before := searchNodes.Load()
+			start := time.Now()
+			analyseMoves(g, *depth, 0)
+			elapsed += time.Since(start)
+			nodes += searchNodes.Load() - before
+			searched++
+		}
+		fmt.Printf("%s: %d positions, profondeur %d, %d nœuds en %s (%s nœuds/s, %s par position)\n",
+			cfg, searched, *depth, nodes, elapsed.Round(time.Millisecond), formatRate(nodes, elapsed),
+			(elapsed / time.Duration(searched)).Round(time.Microsecond))
+		totalNodes += nodes
+		totalTime += elapsed
+	}
+	fmt.Printf("total: %d nœuds en %s (%s nœuds/s)\n", totalNodes, totalTime.Round(time.Millisecond), formatRate(totalNodes, totalTime))
+	return nil
+}
+
+// formatRate affiche un débit de nœuds par seconde.
+func formatRate(nodes int64, d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(nodes)/d.Seconds(), 'f', 0, 64)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhooksPath          = "webhooks.json"
+	webhookDefaultTimeout = 3 * time.Second
+	webhookDefaultRetries = 2
+	webhookRetryDelay     = 200 * time.Millisecond
+)
+
+// errForfeit signale qu'un joueur automatique abandonne la partie après des échecs répétés.
+var errForfeit = errors.New("forfait")
+
+// WebhookBot est un joueur hébergé comme service HTTP: le serveur lui envoie la position en JSON (POST)
+// et attend en retour {"column": n} (n est une ligne en gravité latérale).
+type WebhookBot struct {
+	ID        string `json:"name"`
+	Title     string `json:"label"`
+	URL       string `json:"url"`
+	TimeoutMs int    `json:"timeout_ms"` // délai par requête (0 = valeur par défaut)
+	Retries   *int   `json:"retries"`    // nouvelles tentatives après un échec (absent = valeur par défaut)
+
+	client *http.Client
+}
+
+// WebhookPosition est le corps JSON envoyé au bot.
+type WebhookPosition struct {
+	Rows       int     `json:"rows"`
+	Cols       int     `json:"cols"`
+	Mode       string  `json:"mode"`
+	Gravity    string  `json:"gravity"`
+	Player     int     `json:"player"`
+	Turn       int     `json:"turn"`
+	Board      [][]int `json:"board"`
+	ValidMoves []int   `json:"valid_moves"`
+	History    []int   `json:"history"`
+	TimeLeftMs int64   `json:"time_left_ms,omitempty"`
+	DeadlineMs int64   `json:"deadline_ms"`
+}
+
+// WebhookReply est la réponse attendue du bot.
+type WebhookReply struct {
+	Column *int `json:"column"`
+}
+
+// webhooks contient les bots HTTP configurés, indexés par nom.
+var webhooks = map[string]*WebhookBot{}
+
+// loadWebhooks lit la liste des bots HTTP; un fichier absent n'est pas une erreur.
+func loadWebhooks(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []*WebhookBot
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, b := range list {
+		if b.ID == "" || b.URL == "" {
+			return fmt.Errorf("%s: bot sans nom ou sans URL", path)
+		}
+		if _, ok := aiProfiles[b.ID]; ok {
+			return fmt.Errorf("%s: le bot %q porte le nom d'un profil d'IA", path, b.ID)
+		}
+		if _, ok := engines[b.ID]; ok {
+			return fmt.Errorf("%s: le bot %q porte le nom d'un moteur", path, b.ID)
+		}
+		if b.Title == "" {
+			b.Title = b.ID
+		}
+		b.client = &http.Client{}
+		webhooks[b.ID] = b
+	}
+	return nil
+}
+
+// sortedWebhooks retourne les bots HTTP par ordre alphabétique.
+func sortedWebhooks() []*WebhookBot {
+	list := make([]*WebhookBot, 0, len(webhooks))
+	for _, b := range webhooks {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// retries retourne le nombre de nouvelles tentatives après un échec: 0 pour aucune.
+func (b *WebhookBot) retries() int {
+	if b.Retries == nil {
+		return webhookDefaultRetries
+	}
+	return max(*b.Retries, 0)
+}
+
+func (b *WebhookBot) Name() string  { return b.ID }
+func (b *WebhookBot) Label() string { return b.Title }
+
+// Move envoie la position au bot et retourne son coup. Après Retries nouvelles tentatives
+// infructueuses (erreur réseau, délai dépassé, réponse invalide ou coup illégal), le bot déclare forfait.
+func (b *WebhookBot) Move(g *Game) (int, error) {
+	timeout := webhookDefaultTimeout
+	if b.TimeoutMs > 0 {
+		timeout = time.Duration(b.TimeoutMs) * time.Millisecond
+	}
+	pos := WebhookPosition{
+		Rows:       g.Rows,
+		Cols:       g.Cols,
+		Mode:       g.Mode,
+		Gravity:    gravityName(g.Gravity),
+		Player:     g.CurrentPlayer,
+		Turn:       g.TurnCount,
+		Board:      g.Board,
+		ValidMoves: g.getValidMoves(),
+	}
+	for _, mv := range g.History {
+		pos.History = append(pos.History, mv.Slot)
+	}
+	if g.TimeControl.Enabled() {
+		left := g.TimeLeft(g.CurrentPlayer)
+		pos.TimeLeftMs = left.Milliseconds()
+		if share := left / time.Duration(b.retries()+1); share < timeout {
+			timeout = share
+		}
+	}
+	pos.DeadlineMs = timeout.Milliseconds()
+	body, err := json.Marshal(pos)
+	if err != nil {
+		return -1, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= b.retries(); attempt++ {
+		if attempt > 0 {
+			time.Sleep(webhookRetryDelay)
+		}
+		slot, err := b.request(body, timeout)
+		if err == nil {
+			if _, _, ok := g.landingCell(slot); ok {
+				return slot, nil
+			}
+			err = fmt.Errorf("coup illégal %d", slot)
+		}
+		lastErr = err
+		slog.Warn("bot HTTP", "bot", b.ID, "attempt", attempt+1, "err", err)
+	}
+	return -1, fmt.Errorf("bot %s: %w (%v)", b.ID, errForfeit, lastErr)
+}
+
+// request effectue un appel au bot dans le délai imparti.
+func (b *WebhookBot) request(body []byte, timeout time.Duration) (int, error) {
+	client := *b.client
+	client.Timeout = timeout
+	resp, err := client.Post(b.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("statut HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return -1, err
+	}
+	var reply WebhookReply
+	if err := json.Unmarshal(data, &reply); err != nil || reply.Column == nil {
+		return -1, fmt.Errorf("réponse invalide %q", strings.TrimSpace(string(data)))
+	}
+	return *reply.Column, nil
+}
+
+// runStubBot lance un bot HTTP de test qui joue au hasard, avec un taux d'échec et un délai réglables.
+// Usage: power4 stubbot [-addr :9090] [-fail 0.2] [-delay 0s]
+func runStubBot(args []string) error {
+	fset := flag.NewFlagSet("stubbot", flag.ExitOnError)
+	addr := fset.String("addr", "127.0.0.1:9090", "adresse d'écoute")
+	failRate := fset.Float64("fail", 0, "proportion de requêtes en erreur 500")
+	delay := fset.Duration("delay", 0, "délai avant chaque réponse")
+	fset.Parse(args)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var pos WebhookPosition
+		if err := json.NewDecoder(r.Body).Decode(&pos); err != nil || len(pos.ValidMoves) == 0 {
+			http.Error(w, "position invalide", http.StatusBadRequest)
+			return
+		}
+		time.Sleep(*delay)
+		if rand.Float64() < *failRate {
+			http.Error(w, "échec simulé", http.StatusInternalServerError)
+			return
+		}
+		col := pos.ValidMoves[rand.Intn(len(pos.ValidMoves))]
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"column": %s}`, strconv.Itoa(col))
+	})
+	fmt.Println("Bot de test sur http://" + *addr + "/")
+	return http.ListenAndServe(*addr, nil)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubWebhook démarre un bot HTTP dont les réponses successives sont données par replies
+// (la dernière se répète); chaque réponse est un code de statut et un corps.
+func stubWebhook(t *testing.T, replies ...[2]any) (*WebhookBot, *atomic.Int32, chan WebhookPosition) {
+	var calls atomic.Int32
+	positions := make(chan WebhookPosition, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pos WebhookPosition
+		json.NewDecoder(r.Body).Decode(&pos)
+		select {
+		case positions <- pos:
+		default:
+		}
+		reply := replies[min(int(calls.Add(1))-1, len(replies)-1)]
+		w.WriteHeader(reply[0].(int))
+		w.Write([]byte(reply[1].(string)))
+	}))
+	t.Cleanup(srv.Close)
+	bot := &WebhookBot{ID: "essai", Title: "Essai", URL: srv.URL, TimeoutMs: 500, client: &http.Client{}}
+	return bot, &calls, positions
+}
+
+func TestWebhookMove(t *testing.T) {
+	bot, calls, positions := stubWebhook(t, [2]any{200, `{"column": 4}`})
+	g := newTestGame("gauche")
+	g.DropToken(1)
+	slot, err := bot.Move(g)
+	if err != nil || slot != 4 {
+		t.Fatalf("Move = %d, %v; attendu 4", slot, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d requêtes, attendu 1", calls.Load())
+	}
+	pos := <-positions
+	if pos.Gravity != "left" || pos.Player != 2 || pos.Turn != 1 || len(pos.History) != 1 || pos.History[0] != 1 ||
+		len(pos.ValidMoves) != 6 || pos.Board[1][0] != 1 || pos.DeadlineMs != 500 {
+		t.Errorf("position envoyée: %+v", pos)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	// Deux échecs puis un coup valide: dans la limite des nouvelles tentatives
+	bot, calls, _ := stubWebhook(t, [2]any{500, "panne"}, [2]any{200, `{"col": 3}`}, [2]any{200, `{"column": 3}`})
+	if slot, err := bot.Move(newTestGame("normal")); err != nil || slot != 3 || calls.Load() != 3 {
+		t.Fatalf("Move = %d, %v après %d requêtes", slot, err, calls.Load())
+	}
+
+	// Coup illégal répété: forfait après les 2 nouvelles tentatives par défaut
+	bot, calls, _ = stubWebhook(t, [2]any{200, `{"column": 9}`})
+	_, err := bot.Move(newTestGame("normal"))
+	if !errors.Is(err, errForfeit) || calls.Load() != 3 {
+		t.Fatalf("erreur %v après %d requêtes, attendu un forfait après 3", err, calls.Load())
+	}
+}
+
+func TestWebhookDeadlineFromClock(t *testing.T) {
+	bot, _, positions := stubWebhook(t, [2]any{200, `{"column": 0}`})
+	bot.TimeoutMs = 5000
+	g := newTestGame("normal")
+	g.SetTimeControl(parseTimeControl("coup-3"))
+	if _, err := bot.Move(g); err != nil {
+		t.Fatal(err)
+	}
+	// Le temps restant est partagé entre les tentatives
+	pos := <-positions
+	if pos.TimeLeftMs <= 0 || pos.DeadlineMs > (3*time.Second/3).Milliseconds() {
+		t.Errorf("temps restant %d ms, délai %d ms", pos.TimeLeftMs, pos.DeadlineMs)
+	}
+}
+
+func TestLoadWebhooksRetries(t *testing.T) {
+	saved := webhooks
+	webhooks = map[string]*WebhookBot{}
+	t.Cleanup(func() { webhooks = saved })
+	path := filepath.Join(t.TempDir(), webhooksPath)
+	config := `[{"name": "sans-reprise", "url": "http://127.0.0.1:1", "retries": 0},
+		{"name": "par-defaut", "url": "http://127.0.0.1:1"},
+		{"name": "tenace", "url": "http://127.0.0.1:1", "retries": 5}]`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadWebhooks(path); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"sans-reprise": 0, "par-defaut": webhookDefaultRetries, "tenace": 5} {
+		if got := webhooks[name].retries(); got != want {
+			t.Errorf("%s: %d nouvelles tentatives, attendu %d", name, got, want)
+		}
+	}
+
+	// Sans nouvelle tentative, le premier échec est un forfait
+	bot, calls, _ := stubWebhook(t, [2]any{500, "panne"}, [2]any{200, `{"column": 3}`})
+	bot.Retries = webhooks["sans-reprise"].Retries
+	if _, err := bot.Move(newTestGame("normal")); !errors.Is(err, errForfeit) || calls.Load() != 1 {
+		t.Fatalf("erreur %v après %d requêtes, attendu un forfait après 1", err, calls.Load())
+	}
+}